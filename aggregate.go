@@ -0,0 +1,228 @@
+package journal
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shestakovda/errx"
+)
+
+// Ограничения агрегации, чтобы случайно не построить огромный ряд
+const (
+	aggMaxPoints = 10000
+	aggPageSize  = 1000
+)
+
+/*
+	AggQuery - параметры агрегации записей журнала.
+
+	* From, To - интервал времени старта записей
+	* Step - размер корзины времени, если 0, то весь интервал одной точкой
+	* Services - сервисы для агрегации, если не указаны, то все
+	* ByName - разделять ряды не только по сервису, но и по названию записи
+	* ByTemplate - вместе с ByName, разделять ряды по шаблону первой отметки вместо ее текста
+
+	* Записи без шаблона, например сохраненные до его появления, группируются по названию
+	* Свертки хранят шаблоны, поэтому при группировке по названию без ByTemplate они не используются
+	* Свертки используются, только если From, To и Step совпадают с границами их корзин
+*/
+type AggQuery struct {
+	From       time.Time
+	To         time.Time
+	Step       time.Duration
+	Services   []string
+	ByName     bool
+	ByTemplate bool
+}
+
+// Series - временной ряд агрегатов по одному сервису и, возможно, названию записи
+type Series struct {
+	Service string   `json:"service"`
+	Name    string   `json:"name,omitempty"`
+	Points  []*Point `json:"points"`
+}
+
+// Point - агрегаты записей одной корзины времени
+type Point struct {
+	Time    time.Time     `json:"time"`
+	Count   uint64        `json:"count"`
+	Crashes uint64        `json:"crashes"`
+	Rate    float64       `json:"rate"`
+	P50     time.Duration `json:"p50"`
+	P90     time.Duration `json:"p90"`
+	P99     time.Duration `json:"p99"`
+	Sketch  *Sketch       `json:"-"`
+}
+
+// aggKey - ключ ряда агрегатов
+type aggKey struct {
+	srv  string
+	name string
+}
+
+// aggregator - сборка рядов агрегатов из кратких представлений записей
+type aggregator struct {
+	q    *AggQuery
+	size int
+	srvs map[string]struct{}
+	rows map[aggKey][]*Point
+}
+
+func newAggregator(q *AggQuery) (_ *aggregator, err error) {
+	if q == nil || q.To.Before(q.From) || q.Step < 0 {
+		return nil, ErrValidate.WithDetail("Некорректные параметры агрегации")
+	}
+
+	agg := &aggregator{
+		q:    q,
+		size: 1,
+		rows: make(map[aggKey][]*Point),
+	}
+
+	if q.Step > 0 {
+		agg.size = int(q.To.Sub(q.From)/q.Step) + 1
+	}
+
+	if agg.size > aggMaxPoints {
+		return nil, ErrValidate.WithDetail("Слишком много точек в ряду").WithDebug(errx.Debug{
+			"От момента": q.From.UTC().Format(time.RFC3339Nano),
+			"До момента": q.To.UTC().Format(time.RFC3339Nano),
+			"Шаг":        q.Step.String(),
+		})
+	}
+
+	if len(q.Services) > 0 {
+		agg.srvs = make(map[string]struct{}, len(q.Services))
+		for i := range q.Services {
+			agg.srvs[strings.ToLower(q.Services[i])] = struct{}{}
+		}
+	}
+
+	return agg, nil
+}
+
+// add - учет одной записи, записи вне интервала или чужих сервисов пропускаются
+func (a *aggregator) add(sum *Summary) {
+	if !a.accept(sum.Service, sum.Start) {
+		return
+	}
+
+	name := sum.Name
+
+	if a.q.ByTemplate && sum.Tmpl != "" {
+		name = sum.Tmpl
+	}
+
+	pnt := a.point(sum.Service, name, sum.Start)
+	pnt.Count++
+	pnt.Sketch.Add(sum.Total)
+
+	if sum.Fail {
+		pnt.Crashes++
+	}
+}
+
+// addBin - учет корзины наброска из свертки, сразу для нескольких записей
+func (a *aggregator) addBin(srv, name string, start time.Time, fail bool, bin int32, cnt uint64) {
+	if !a.accept(srv, start) {
+		return
+	}
+
+	pnt := a.point(srv, name, start)
+	pnt.Count += cnt
+	pnt.Sketch.addBin(bin, cnt)
+
+	if fail {
+		pnt.Crashes += cnt
+	}
+}
+
+func (a *aggregator) accept(srv string, start time.Time) bool {
+	if start.Before(a.q.From) || start.After(a.q.To) {
+		return false
+	}
+
+	if a.srvs != nil {
+		if _, ok := a.srvs[strings.ToLower(srv)]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// point - точка ряда для записи, ряд создается целиком при первом обращении
+func (a *aggregator) point(srv, name string, start time.Time) *Point {
+	key := aggKey{srv: srv}
+
+	if a.q.ByName {
+		key.name = name
+	}
+
+	row, ok := a.rows[key]
+
+	if !ok {
+		row = make([]*Point, a.size)
+
+		for i := range row {
+			row[i] = &Point{
+				Time:   a.q.From.Add(time.Duration(i) * a.q.Step).UTC(),
+				Sketch: NewSketch(),
+			}
+		}
+
+		a.rows[key] = row
+	}
+
+	if a.q.Step == 0 {
+		return row[0]
+	}
+
+	return row[int(start.Sub(a.q.From)/a.q.Step)]
+}
+
+// series - итоговые ряды с процентилями, упорядочены по сервису и названию
+func (a *aggregator) series() []*Series {
+	res := make([]*Series, 0, len(a.rows))
+
+	for key, row := range a.rows {
+		for _, pnt := range row {
+			if pnt.Count > 0 {
+				pnt.Rate = float64(pnt.Crashes) / float64(pnt.Count)
+			}
+
+			pnt.P50 = pnt.Sketch.Quantile(0.5)
+			pnt.P90 = pnt.Sketch.Quantile(0.9)
+			pnt.P99 = pnt.Sketch.Quantile(0.99)
+		}
+
+		res = append(res, &Series{
+			Service: key.srv,
+			Name:    key.name,
+			Points:  row,
+		})
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Service != res[j].Service {
+			return res[i].Service < res[j].Service
+		}
+		return res[i].Name < res[j].Name
+	})
+
+	return res
+}
+
+// scan - агрегация всех записей курсора по их кратким представлениям
+func (a *aggregator) scan(ctx context.Context, cur Cursor) (_ []*Series, err error) {
+	if err = cur.Each(ctx, aggPageSize, func(mod Model) error {
+		a.add(mod.ExportSummary())
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return a.series(), nil
+}
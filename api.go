@@ -0,0 +1,141 @@
+package journal
+
+import (
+	"github.com/shestakovda/errx"
+	"github.com/shestakovda/journal/crash"
+)
+
+// APIOption - настройка представления записи журнала для внешних клиентов
+type APIOption func(*apiOptions)
+
+/*
+	APIMaxVerbose - в представление попадают только отметки с уровнем логирования не выше указанного.
+
+	* Уровень отметки задается через Verbose.V перед ее созданием
+*/
+func APIMaxVerbose(lvl int) APIOption {
+	return func(o *apiOptions) {
+		o.verb = lvl
+		o.limit = true
+	}
+}
+
+/*
+	APIHideTypes - у отметок с моделями указанных типов не выводится ссылка на модель.
+
+	* Для внутренних моделей, идентификаторы которых не должны попадать к внешним клиентам
+*/
+func APIHideTypes(types ...ModelType) APIOption {
+	return func(o *apiOptions) {
+		for i := range types {
+			if types[i] != nil {
+				o.hide[types[i].ID()] = true
+			}
+		}
+	}
+}
+
+/*
+	APIWithCrash - для отметок с ошибками добавляется краткий отчет в формате RFC 7807.
+
+	* Отчеты загружаются одним пакетом, если какой-то не загрузился, возвращается ErrSelect
+	* APIHideTypes скрывает только ссылку на отчет, сам краткий отчет все равно выводится
+*/
+func APIWithCrash() APIOption {
+	return func(o *apiOptions) {
+		o.crash = true
+	}
+}
+
+type apiOptions struct {
+	verb  int
+	limit bool
+	crash bool
+	hide  map[int]bool
+	fails []apiFail
+}
+
+// apiFail - отметка с ошибкой, для которой нужен краткий отчет
+type apiFail struct {
+	stg *StageAPI
+	rid string
+}
+
+func getAPIOptions(args []APIOption) *apiOptions {
+	o := &apiOptions{
+		hide: make(map[int]bool),
+	}
+
+	for i := range args {
+		args[i](o)
+	}
+
+	return o
+}
+
+// skip - отметка слишком подробная для этого представления
+func (o *apiOptions) skip(verb int) bool {
+	return o.limit && verb > o.verb
+}
+
+// stage - заполнение ссылки на модель, с учетом скрытых типов
+func (o *apiOptions) stage(s *StageAPI, mtp ModelType, mid string) *StageAPI {
+	if mid == "" {
+		return s
+	}
+
+	// Отчет нужен и для скрытой ссылки, поэтому отметка запоминается до проверки типа
+	if o.crash && mtp.ID() == ModelTypeCrash.ID() {
+		o.fails = append(o.fails, apiFail{stg: s, rid: mid})
+	}
+
+	if o.hide[mtp.ID()] {
+		return s
+	}
+
+	s.EnID = mid
+	s.Type = mtp.String()
+	return s
+}
+
+// exportCrashAPI - краткие отчеты об ошибках для отметок, все отчеты загружаются одним пакетом
+func exportCrashAPI(crf crash.Factory, fails []apiFail) (err error) {
+	var ids, miss []string
+	var crms []crash.Model
+
+	uniq := make(map[string]bool)
+
+	for i := range fails {
+		if !uniq[fails[i].rid] {
+			uniq[fails[i].rid] = true
+			ids = append(ids, fails[i].rid)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if crms, miss, err = crf.ByIDs(ids...); err != nil {
+		return ErrSelect.WithReason(err)
+	}
+
+	if len(miss) > 0 {
+		return ErrSelect.WithReason(crash.ErrNotFound).WithDebug(errx.Debug{
+			"Отчеты": miss,
+		})
+	}
+
+	// Все отчеты найдены, поэтому идут в том же порядке, что и ids
+	reps := make(map[string]*crash.RFC, len(crms))
+
+	for i := range crms {
+		reps[ids[i]] = crms[i].ExportRFC()
+	}
+
+	for i := range fails {
+		fails[i].stg.Fail = reps[fails[i].rid]
+	}
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package journal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Breakdown - распределение времени по отметкам записей, сгруппированных по названию
+type Breakdown struct {
+	Groups []*BreakdownGroup `json:"groups"`
+}
+
+// BreakdownGroup - записи с одинаковым названием
+type BreakdownGroup struct {
+	Name   string            `json:"name"`
+	Count  uint64            `json:"count"`
+	Mean   time.Duration     `json:"mean"`
+	Stages []*BreakdownStage `json:"stages"`
+}
+
+// BreakdownStage - одна и та же отметка во всех записях группы
+type BreakdownStage struct {
+	Pos   int           `json:"pos"`
+	Text  string        `json:"text"`
+	Count uint64        `json:"count"`
+	Mean  time.Duration `json:"mean"`
+	P50   time.Duration `json:"p50"`
+	P90   time.Duration `json:"p90"`
+	P99   time.Duration `json:"p99"`
+	Share float64       `json:"share"`
+}
+
+/*
+	StageBreakdown - на что уходит время внутри записей, по всем оставшимся записям курсора.
+
+	* ctx - контекст перебора, при отмене возвращается его ошибка
+	* cur - курсор с записями, перебирается до конца
+	* size - размер загружаемой страницы
+
+	* Записи группируются по названию, отметки сопоставляются по тексту и номеру его повтора в записи
+	* Если у отметки есть шаблон, то вместо текста используется он, чтобы не разделять похожие отметки
+	* Для каждой отметки считается среднее и процентили ожидания, а также доля от общего времени группы
+	* Отметки упорядочены по средней позиции в записи, группы - по убыванию кол-ва записей
+*/
+func StageBreakdown(ctx context.Context, cur Cursor, size uint) (_ *Breakdown, err error) {
+	brk := newBreakdown()
+
+	if err = cur.Each(ctx, size, func(mod Model) (exp error) {
+		var ent *Entry
+
+		if ent, exp = mod.Export(false); exp != nil {
+			return ErrSelect.WithReason(exp)
+		}
+
+		brk.add(ent)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return brk.result(), nil
+}
+
+// String - представление в виде текстовой таблицы
+func (b *Breakdown) String() string {
+	var buf strings.Builder
+
+	tab := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+
+	for _, grp := range b.Groups {
+		fmt.Fprintf(tab, "Название: %s\tЗаписей: %d\tСреднее: %s\n", grp.Name, grp.Count, grp.Mean)
+		fmt.Fprintln(tab, "#\tОтметка\tКол-во\tСреднее\tp50\tp90\tp99\tДоля")
+
+		for _, stg := range grp.Stages {
+			fmt.Fprintf(tab, "%d\t%s\t%d\t%s\t%s\t%s\t%s\t%s%%\n",
+				stg.Pos, stg.Text, stg.Count, stg.Mean, stg.P50, stg.P90, stg.P99,
+				strconv.FormatFloat(100*stg.Share, 'f', 1, 64),
+			)
+		}
+
+		fmt.Fprintln(tab)
+	}
+
+	tab.Flush()
+	return buf.String()
+}
+
+// brkKey - отметка группы: текст и номер его повтора в записи
+type brkKey struct {
+	text string
+	num  int
+}
+
+type brkStage struct {
+	pos   int
+	wait  time.Duration
+	count uint64
+	sk    *Sketch
+}
+
+type brkGroup struct {
+	count  uint64
+	total  time.Duration
+	stages map[brkKey]*brkStage
+}
+
+// breakdown - накопление статистики по записям
+type breakdown struct {
+	groups map[string]*brkGroup
+}
+
+func newBreakdown() *breakdown {
+	return &breakdown{groups: make(map[string]*brkGroup)}
+}
+
+func (b *breakdown) add(ent *Entry) {
+	var name string
+
+	if len(ent.Chain) > 0 {
+		name = stageName(ent.Chain[0])
+	}
+
+	grp, ok := b.groups[name]
+
+	if !ok {
+		grp = &brkGroup{stages: make(map[brkKey]*brkStage)}
+		b.groups[name] = grp
+	}
+
+	grp.count++
+	grp.total += ent.Total
+
+	seen := make(map[string]int, len(ent.Chain))
+
+	for i := range ent.Chain {
+		text := stageName(ent.Chain[i])
+		key := brkKey{text: text, num: seen[text]}
+		seen[text]++
+
+		stg, ok := grp.stages[key]
+
+		if !ok {
+			stg = &brkStage{sk: NewSketch()}
+			grp.stages[key] = stg
+		}
+
+		stg.pos += i
+		stg.count++
+		stg.wait += ent.Chain[i].Wait
+		stg.sk.Add(ent.Chain[i].Wait)
+	}
+}
+
+func (b *breakdown) result() *Breakdown {
+	res := &Breakdown{Groups: make([]*BreakdownGroup, 0, len(b.groups))}
+
+	for name, grp := range b.groups {
+		out := &BreakdownGroup{
+			Name:   name,
+			Count:  grp.count,
+			Mean:   grp.total / time.Duration(grp.count),
+			Stages: make([]*BreakdownStage, 0, len(grp.stages)),
+		}
+
+		// Средняя позиция в сотых долях, чтобы отметки не слипались при округлении
+		pos := make(map[*BreakdownStage]int, len(grp.stages))
+
+		for key, stg := range grp.stages {
+			row := &BreakdownStage{
+				Text:  key.text,
+				Count: stg.count,
+				Mean:  stg.wait / time.Duration(stg.count),
+				P50:   stg.sk.Quantile(0.5),
+				P90:   stg.sk.Quantile(0.9),
+				P99:   stg.sk.Quantile(0.99),
+			}
+
+			if grp.total > 0 {
+				row.Share = float64(stg.wait) / float64(grp.total)
+			}
+
+			pos[row] = 100 * stg.pos / int(stg.count)
+			out.Stages = append(out.Stages, row)
+		}
+
+		sort.Slice(out.Stages, func(i, j int) bool {
+			if pos[out.Stages[i]] != pos[out.Stages[j]] {
+				return pos[out.Stages[i]] < pos[out.Stages[j]]
+			}
+			return out.Stages[i].Text < out.Stages[j].Text
+		})
+
+		for i := range out.Stages {
+			out.Stages[i].Pos = i
+		}
+
+		res.Groups = append(res.Groups, out)
+	}
+
+	sort.Slice(res.Groups, func(i, j int) bool {
+		if res.Groups[i].Count != res.Groups[j].Count {
+			return res.Groups[i].Count > res.Groups[j].Count
+		}
+		return res.Groups[i].Name < res.Groups[j].Name
+	})
+
+	return res
+}
+
+// stageName - шаблон отметки, а если его нет, то текст
+func stageName(s *Stage) string {
+	if s.Tmpl != "" {
+		return s.Tmpl
+	}
+	return s.Text
+}
@@ -0,0 +1,81 @@
+package journal
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type BreakdownSuite struct {
+	suite.Suite
+}
+
+func (s *BreakdownSuite) TestGroups() {
+	brk := newBreakdown()
+
+	for i := 1; i <= 4; i++ {
+		wait := time.Duration(i) * time.Millisecond
+
+		brk.add(&Entry{
+			Total: 10 * wait,
+			Chain: []*Stage{
+				{Text: "create order", Wait: wait},
+				{Text: "load user", Wait: 2 * wait},
+				{Text: "load user", Wait: 3 * wait},
+				{Text: "save order", Wait: 4 * wait},
+			},
+		})
+	}
+
+	brk.add(&Entry{
+		Total: time.Second,
+		Chain: []*Stage{{Text: "ping", Wait: time.Second}},
+	})
+
+	res := brk.result()
+
+	if s.Len(res.Groups, 2) {
+		grp := res.Groups[0]
+		s.Equal("create order", grp.Name)
+		s.Equal(uint64(4), grp.Count)
+		s.Equal(25*time.Millisecond, grp.Mean)
+
+		// Повторы одного текста остаются отдельными отметками, в порядке следования
+		if s.Len(grp.Stages, 4) {
+			s.Equal("load user", grp.Stages[1].Text)
+			s.Equal("load user", grp.Stages[2].Text)
+			s.Equal(5*time.Millisecond, grp.Stages[1].Mean)
+			s.Equal(7500*time.Microsecond, grp.Stages[2].Mean)
+			s.InDelta(0.4, grp.Stages[3].Share, 1e-9)
+			s.Equal(3, grp.Stages[3].Pos)
+		}
+
+		s.Equal("ping", res.Groups[1].Name)
+	}
+
+	// Отметки с шаблоном группируются по нему, а не по тексту
+	tpl := newBreakdown()
+
+	for i := 1; i <= 2; i++ {
+		tpl.add(&Entry{
+			Total: time.Second,
+			Chain: []*Stage{
+				{Text: "load user " + strconv.Itoa(i), Tmpl: "load user %d", Wait: time.Second},
+			},
+		})
+	}
+
+	if grp := tpl.result().Groups; s.Len(grp, 1) && s.Len(grp[0].Stages, 1) {
+		s.Equal("load user %d", grp[0].Name)
+		s.Equal(uint64(2), grp[0].Stages[0].Count)
+	}
+
+	// Оба представления
+	s.Contains(res.String(), "save order")
+
+	if buf, err := json.Marshal(res); s.NoError(err) {
+		s.Contains(string(buf), `"share":0.4`)
+	}
+}
@@ -16,4 +16,8 @@ var (
 	ErrInsert   = errx.New("Ошибка сохранения записи журнала").WithReason(errx.ErrInternal)
 	ErrNotFound = errx.New("Не найдены подходящие записи журнала").WithReason(errx.ErrNotFound)
 	ErrValidate = errx.New("Ошибка валидации входных данных").WithReason(errx.ErrBadRequest)
+	ErrExpired  = errx.New("Истек срок действия курсора журнала").WithReason(errx.ErrNotFound)
+	ErrSweep    = errx.New("Ошибка очистки курсоров журнала").WithReason(errx.ErrInternal)
+
+	ErrNotSupported = errx.New("Не поддерживается этой реализацией журнала").WithReason(errx.ErrNotImplemented)
 )
@@ -0,0 +1,139 @@
+package crash
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Catalog - перечень кодов ошибок сервиса для документации
+type Catalog struct {
+	Service string         `json:"service"`
+	Codes   []*CatalogCode `json:"codes"`
+}
+
+/*
+	CatalogCode - описание одного кода ошибки в каталоге.
+
+	* Titles - заголовок на всех языках, если есть переводы
+	* Priority - приоритет правила, среди подходящих срабатывает правило с большим приоритетом
+	* Triggers - описания внутренних ошибок, на которые формируется этот код
+	* Fallback - код по-умолчанию, для ошибок без правила
+*/
+type CatalogCode struct {
+	Code     string            `json:"code"`
+	Status   uint16            `json:"status"`
+	Number   int               `json:"number"`
+	Title    string            `json:"title"`
+	Titles   map[string]string `json:"titles,omitempty"`
+	Link     string            `json:"link,omitempty"`
+	Priority int               `json:"priority,omitempty"`
+	Triggers []string          `json:"triggers,omitempty"`
+	Fallback bool              `json:"fallback,omitempty"`
+}
+
+// Localize - копия каталога, в которой заголовки выбраны по значению Accept-Language
+func (c *Catalog) Localize(accept string) *Catalog {
+	res := &Catalog{
+		Service: c.Service,
+		Codes:   make([]*CatalogCode, len(c.Codes)),
+	}
+
+	for i := range c.Codes {
+		code := *c.Codes[i]
+		code.Title = localize(accept, code.Title, code.Titles)
+		res.Codes[i] = &code
+	}
+
+	return res
+}
+
+// WriteJSON - каталог в формате JSON
+func (c *Catalog) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+/*
+	WriteMarkdown - каталог в формате Markdown.
+
+	* Перед каждым кодом ставится якорь с его названием, чтобы ссылки вида ERR_BASE_URL#код вели на описание
+*/
+func (c *Catalog) WriteMarkdown(w io.Writer) (err error) {
+	var buf strings.Builder
+
+	fmt.Fprintf(&buf, "# Коды ошибок %s\n\n", c.Service)
+	buf.WriteString("| Код | Статус | Заголовок |\n")
+	buf.WriteString("|---|---|---|\n")
+
+	for _, code := range c.Codes {
+		fmt.Fprintf(&buf, "| [%s](#%s) | %d | %s |\n", code.Code, code.Code, code.Status, mdEscape(code.Title))
+	}
+
+	for _, code := range c.Codes {
+		fmt.Fprintf(&buf, "\n<a id=\"%s\"></a>\n## %s\n\n", code.Code, code.Code)
+		fmt.Fprintf(&buf, "**%d %s**. %s\n", code.Status, http.StatusText(int(code.Status)), mdEscape(code.Title))
+
+		if len(code.Triggers) > 0 {
+			buf.WriteString("\nВозникает при ошибках:\n\n")
+
+			for i := range code.Triggers {
+				fmt.Fprintf(&buf, "- %s\n", mdEscape(code.Triggers[i]))
+			}
+		}
+	}
+
+	_, err = io.WriteString(w, buf.String())
+	return err
+}
+
+/*
+	CatalogHandler - http обработчик, который отдает каталог провайдера.
+
+	* Каталог формируется на каждый запрос, поэтому в нем видны все регистрации
+	* Заголовки выбираются по Accept-Language
+	* По-умолчанию отдается Markdown, JSON - если он указан в Accept или в параметре format=json
+	* Его удобно повесить на адрес из ERR_BASE_URL, тогда ссылки в отчетах ведут на описания кодов
+*/
+func CatalogHandler(prv Provider) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		var buf bytes.Buffer
+		var typ string
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		cat := prv.Catalog().Localize(r.Header.Get("Accept-Language"))
+
+		// Сначала формируем каталог целиком, чтобы при ошибке не отдать половину ответа со статусом 200
+		if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+			typ = "application/json; charset=utf-8"
+			err = cat.WriteJSON(&buf)
+		} else {
+			typ = "text/markdown; charset=utf-8"
+			err = cat.WriteMarkdown(&buf)
+		}
+
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", typ)
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+		// Заголовки уже отправлены, об ошибке отправки клиенту сообщить нельзя
+		_, _ = buf.WriteTo(w)
+	})
+}
+
+// mdEscape - текст без символов, которые ломают разметку таблиц и списков
+var mdEscape = strings.NewReplacer("|", "\\|", "\n", " ", "\r", "").Replace
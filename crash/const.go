@@ -24,4 +24,17 @@ var (
 	ErrInsert     = errx.New("Ошибка сохранения отчета об ошибке")
 	ErrNotFound   = errx.New("Не найден подходящий отчет об ошибке")
 	ErrIDValidate = errx.New("Некорректный идентификатор ошибки")
+
+	ErrQueryValidate = errx.New("Некорректный поисковый запрос").WithReason(errx.ErrBadRequest)
+	ErrExpired       = errx.New("Истек срок действия курсора отчетов").WithReason(ErrNotFound)
+	ErrSweep         = errx.New("Ошибка очистки курсоров отчетов").WithReason(errx.ErrInternal)
+	ErrNotSupported  = errx.New("Не поддерживается этой реализацией отчетов").WithReason(errx.ErrNotImplemented)
+
+	ErrTransition     = errx.New("Недопустимая смена состояния проблемы").WithReason(errx.ErrUnprocessable)
+	ErrTriageValidate = errx.New("Некорректные параметры разбора проблемы").WithReason(errx.ErrBadRequest)
+	ErrIssueSort      = errx.New("Ошибка перестроения порядка проблем").WithReason(errx.ErrInternal)
+
+	ErrRegistryValidate = errx.New("Некорректный реестр кодов ошибок").WithReason(errx.ErrBadRequest)
+
+	ErrWriteProblem = errx.New("Ошибка отправки отчета об ошибке")
 )
@@ -0,0 +1,66 @@
+package crash
+
+import (
+	"net/http"
+
+	"github.com/shestakovda/errx"
+)
+
+// ErrxPriority - приоритет правил ErrxRules, ниже нулевого, чтобы любые правила приложения срабатывали раньше
+var ErrxPriority = -100
+
+/*
+	ErrxRules - набор правил для стандартных причин errx, например errx.ErrNotFound или errx.ErrBadRequest.
+
+	* Статус соответствует причине, номер 0, заголовки на русском и английском
+	* Возвращает новые правила при каждом вызове, их можно изменить перед регистрацией
+*/
+func ErrxRules() []*Rule {
+	rules := []struct {
+		status int
+		reason error
+		titles map[string]string
+	}{
+		{http.StatusBadRequest, errx.ErrBadRequest, map[string]string{"ru": "Некорректный запрос", "en": "Bad request"}},
+		{http.StatusUnauthorized, errx.ErrUnauthorized, map[string]string{"ru": "Требуется авторизация", "en": "Unauthorized"}},
+		{http.StatusForbidden, errx.ErrForbidden, map[string]string{"ru": "Доступ запрещен", "en": "Forbidden"}},
+		{http.StatusNotFound, errx.ErrNotFound, map[string]string{"ru": "Не найдено", "en": "Not found"}},
+		{http.StatusMethodNotAllowed, errx.ErrNotAllowed, map[string]string{"ru": "Действие не разрешено", "en": "Method not allowed"}},
+		{http.StatusNotAcceptable, errx.ErrNotAcceptable, map[string]string{"ru": "Неприемлемый формат ответа", "en": "Not acceptable"}},
+		{http.StatusUnprocessableEntity, errx.ErrUnprocessable, map[string]string{"ru": "Запрос не может быть обработан", "en": "Unprocessable entity"}},
+		{http.StatusInternalServerError, errx.ErrInternal, map[string]string{"ru": "Внутренняя ошибка сервиса", "en": "Internal server error"}},
+		{http.StatusNotImplemented, errx.ErrNotImplemented, map[string]string{"ru": "Не реализовано", "en": "Not implemented"}},
+		{http.StatusServiceUnavailable, errx.ErrUnavailable, map[string]string{"ru": "Сервис временно недоступен", "en": "Service unavailable"}},
+	}
+
+	res := make([]*Rule, len(rules))
+
+	for i := range rules {
+		res[i] = &Rule{
+			Status:   rules[i].status,
+			Title:    rules[i].titles[DefaultLang],
+			Titles:   rules[i].titles,
+			Priority: ErrxPriority,
+			Triggers: []error{rules[i].reason},
+		}
+
+		// Если язык по-умолчанию изменен на тот, которого нет в наборе
+		if res[i].Title == "" {
+			res[i].Title = rules[i].titles["en"]
+		}
+	}
+
+	return res
+}
+
+/*
+	RegisterErrxRules - регистрация ErrxRules в провайдере.
+
+	* Без них ошибки, для которых не зарегистрировано правило, отдаются клиентам как 500,
+	* даже если по причине понятно, что это, например, 404
+*/
+func RegisterErrxRules(prv Provider) {
+	for _, rule := range ErrxRules() {
+		prv.RegisterRule(rule)
+	}
+}
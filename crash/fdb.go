@@ -52,6 +52,46 @@ func (f *fdbFactory) ByID(id string) (_ Model, err error) {
 	return m, nil
 }
 
+func (f *fdbFactory) ByIDs(ids ...string) (res []Model, miss []string, err error) {
+	var uids []typex.UUID
+
+	if uids, err = parseIDs(ids); err != nil {
+		return nil, nil, err
+	}
+
+	recs := make([]fdbx.Record, len(uids))
+	lost := make(map[fdbx.Record]bool)
+
+	for i := range uids {
+		recs[i] = &fdbModel{ID: uids[i].Hex(), fac: f}
+	}
+
+	// Все чтения запускаются сразу, а ненайденные отчеты просто отмечаем
+	onNotFound := func(rec fdbx.Record) error {
+		lost[rec] = true
+		return nil
+	}
+
+	if err = f.db.Load(onNotFound, recs...); err != nil {
+		return nil, nil, ErrSelect.WithReason(err).WithDebug(errx.Debug{
+			"IDs": ids,
+		})
+	}
+
+	res = make([]Model, 0, len(recs))
+
+	for i := range recs {
+		if lost[recs[i]] {
+			miss = append(miss, ids[i])
+			continue
+		}
+
+		res = append(res, recs[i].(*fdbModel))
+	}
+
+	return res, miss, nil
+}
+
 func (f *fdbFactory) ByDateCode(from, to time.Time, code string) (_ []Model, err error) {
 	var ids []string
 
@@ -102,6 +142,51 @@ func (f *fdbFactory) ByDateCode(from, to time.Time, code string) (_ []Model, err
 	return mods, nil
 }
 
+// Select - в этой версии курсоры не поддерживаются
+func (f *fdbFactory) Select(q *Query) (Cursor, error) {
+	return nil, ErrNotSupported.WithDebug(errx.Debug{"Выборка": "курсором"})
+}
+
+// Cursor - в этой версии курсоры не поддерживаются
+func (f *fdbFactory) Cursor(id string) (Cursor, error) {
+	return nil, ErrNotSupported.WithDebug(errx.Debug{"Курсор": id})
+}
+
+// Issue - в этой версии проблемы не учитываются
+func (f *fdbFactory) Issue(fingerprint string) (*Issue, error) {
+	return nil, ErrNotSupported.WithDebug(errx.Debug{"Отпечаток": fingerprint})
+}
+
+// Issues - в этой версии проблемы не учитываются
+func (f *fdbFactory) Issues(IssueOrder, uint) ([]*Issue, error) {
+	return nil, ErrNotSupported.WithDebug(errx.Debug{"Список": "проблем"})
+}
+
+// OpenIssues - в этой версии проблемы не учитываются
+func (f *fdbFactory) OpenIssues(service string, _ uint) ([]*Issue, error) {
+	return nil, ErrNotSupported.WithDebug(errx.Debug{"Сервис": service})
+}
+
+// SetIssueState - в этой версии проблемы не учитываются
+func (f *fdbFactory) SetIssueState(fingerprint string, _ IssueState, _, _ string) (*Issue, error) {
+	return nil, ErrNotSupported.WithDebug(errx.Debug{"Отпечаток": fingerprint})
+}
+
+// AssignIssue - в этой версии проблемы не учитываются
+func (f *fdbFactory) AssignIssue(fingerprint, _, _ string) (*Issue, error) {
+	return nil, ErrNotSupported.WithDebug(errx.Debug{"Отпечаток": fingerprint})
+}
+
+// NoteIssue - в этой версии проблемы не учитываются
+func (f *fdbFactory) NoteIssue(fingerprint, _, _ string) (*Issue, error) {
+	return nil, ErrNotSupported.WithDebug(errx.Debug{"Отпечаток": fingerprint})
+}
+
+// Search - в этой версии индексируются только сообщения целиком
+func (f *fdbFactory) Search(query string, _, _ time.Time, _ uint) (Cursor, error) {
+	return nil, ErrNotSupported.WithDebug(errx.Debug{"Запрос": query})
+}
+
 func (f *fdbFactory) newRecord(ver uint8, id string) (fdbx.Record, error) {
 	return &fdbModel{ID: id, fac: f}, nil
 }
@@ -114,6 +199,8 @@ type fdbModel struct {
 	Status  uint16      `json:"status"`
 	Created time.Time   `json:"created"`
 	Errx    []*fdbError `json:"chain"`
+	Service string      `json:"service,omitempty"`
+	Trace   string      `json:"trace,omitempty"`
 
 	fac *fdbFactory
 }
@@ -132,6 +219,8 @@ func (m *fdbModel) Import(r *Report) (err error) {
 	m.Title = r.Title
 	m.Status = r.Status
 	m.Created = r.Created
+	m.Service = r.Service
+	m.Trace = r.Trace
 	m.Errx = make([]*fdbError, len(r.Entries))
 
 	for i := range r.Entries {
@@ -159,6 +248,8 @@ func (m *fdbModel) Export() (r *Report) {
 		Status:  m.Status,
 		Created: m.Created,
 		Entries: make([]*ReportEntry, len(m.Errx)),
+		Service: m.Service,
+		Trace:   m.Trace,
 	}
 
 	for i := range m.Errx {
@@ -170,6 +261,7 @@ func (m *fdbModel) Export() (r *Report) {
 		}
 	}
 
+	r.Params = invalidParams(nil, r.Entries)
 	return r
 }
 
@@ -181,12 +273,17 @@ func (m *fdbModel) ExportRFC() *RFC {
 		Title:   m.Title,
 		Status:  m.Status,
 		Created: m.Created.Format(time.RFC3339Nano),
+		Trace:   m.Trace,
 	}
 
 	if v.Link == "" {
 		v.Link = BlankLink
 	}
 
+	if m.Status < 500 {
+		v.Params = m.Export().Params
+	}
+
 	parts := make([]string, 0, len(m.Errx))
 	for i := len(m.Errx) - 1; i >= 0; i-- {
 		if m.Errx[i].Detail != "" {
@@ -282,3 +379,20 @@ func Unix(t time.Time) []byte {
 	binary.BigEndian.PutUint64(buf[:], uint64(t.UTC().UnixNano()))
 	return buf[:]
 }
+
+// parseIDs - разбор списка идентификаторов для пакетной загрузки, пустой список тоже ошибка
+func parseIDs(ids []string) (uids []typex.UUID, err error) {
+	if len(ids) == 0 {
+		return nil, ErrIDValidate.WithDetail("Не указаны идентификаторы")
+	}
+
+	uids = make([]typex.UUID, len(ids))
+
+	for i := range ids {
+		if uids[i], err = typex.ParseUUID(ids[i]); err != nil {
+			return nil, ErrIDValidate.WithReason(err)
+		}
+	}
+
+	return uids, nil
+}
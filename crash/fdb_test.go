@@ -110,6 +110,14 @@ func (s *FdbSuite) TestByID() {
 			s.True(errx.Is(err, typex.ErrUUIDInvalid))
 		}
 
+		if _, _, err = fac.ByIDs(); s.Error(err) {
+			s.True(errx.Is(err, ErrIDValidate))
+		}
+
+		if _, _, err = fac.ByIDs("unknown"); s.Error(err) {
+			s.True(errx.Is(err, ErrIDValidate))
+		}
+
 		uid := typex.NewUUID().Hex()
 
 		fdb.FLoad = func(fdbx.RecordHandler, ...fdbx.Record) error { return assert.AnError }
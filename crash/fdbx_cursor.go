@@ -0,0 +1,199 @@
+package crash
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	"github.com/shestakovda/errx"
+	"github.com/shestakovda/fdbx/v2"
+	"github.com/shestakovda/fdbx/v2/mvcc"
+	"github.com/shestakovda/fdbx/v2/orm"
+	"github.com/shestakovda/journal/models"
+	"github.com/shestakovda/typex"
+)
+
+// Размер страницы курсора, если в запросе он не указан
+const cursorPage = 100
+
+func newFdbxCursor(fac *fdbxFactory, qid string, q *Query, que orm.Query) *fdbxCursor {
+	return &fdbxCursor{
+		qid: qid,
+		fac: fac,
+		qry: q,
+		que: que.Where(filterQuery(q)),
+	}
+}
+
+func loadFdbxCursor(fac *fdbxFactory, qid string) (_ *fdbxCursor, err error) {
+	var q *Query
+	var row fdbx.Pair
+	var uid typex.UUID
+	var que orm.Query
+
+	dbg := errx.Debug{"Курсор": qid}
+
+	if uid, err = typex.ParseUUID(qid); err != nil {
+		return nil, ErrIDValidate.WithReason(err).WithDebug(dbg)
+	}
+
+	if err = fac.checkCursorTTL(uid); err != nil {
+		return nil, err
+	}
+
+	if row, err = fac.tx.Select(cursorKey(fac.tbl.ID(), uid)); err != nil {
+		if errx.Is(err, mvcc.ErrNotFound) {
+			return nil, ErrNotFound.WithReason(err).WithDebug(dbg)
+		}
+
+		return nil, ErrSelect.WithReason(err).WithDebug(dbg)
+	}
+
+	if q = parseQuery(row.Value()); q == nil {
+		return nil, ErrSelect.WithDetail("Некорректные параметры курсора").WithDebug(dbg)
+	}
+
+	if que, err = fac.tbl.Cursor(fac.tx, qid); err != nil {
+		if errx.Is(err, orm.ErrNotFound) {
+			return nil, ErrNotFound.WithReason(err).WithDebug(dbg)
+		}
+
+		return nil, ErrSelect.WithReason(err).WithDebug(dbg)
+	}
+
+	return newFdbxCursor(fac, qid, q, que), nil
+}
+
+type fdbxCursor struct {
+	empty bool
+
+	qid string
+	qry *Query
+	que orm.Query
+	fac *fdbxFactory
+}
+
+func (c *fdbxCursor) ID() string {
+	return c.qid
+}
+
+func (c *fdbxCursor) Empty() bool {
+	return c.empty
+}
+
+func (c *fdbxCursor) NextPage(size uint) (res []Model, err error) {
+	var rows []fdbx.Pair
+
+	if c.empty {
+		return nil, nil
+	}
+
+	if size == 0 {
+		size = c.qry.Page
+	}
+
+	if rows, err = c.que.Page(int(size)).Next(); err != nil {
+		return nil, ErrSelect.WithReason(err).WithDebug(errx.Debug{
+			"Курсор":          c.qid,
+			"Размер страницы": size,
+		})
+	}
+
+	if len(rows) < int(size) {
+		c.empty = true
+	}
+
+	res = make([]Model, len(rows))
+	for i := range rows {
+		res[i] = loadFdbxModel(c.fac, typex.UUID(rows[i].Key().Bytes()), rows[i].Value())
+	}
+
+	return res, nil
+}
+
+func (c *fdbxCursor) Close() (err error) {
+	var uid typex.UUID
+
+	c.empty = true
+	dbg := errx.Debug{"Курсор": c.qid}
+
+	if err = c.que.Drop(); err != nil {
+		return ErrSelect.WithReason(err).WithDebug(dbg)
+	}
+
+	if uid, err = typex.ParseUUID(c.qid); err != nil {
+		return ErrIDValidate.WithReason(err).WithDebug(dbg)
+	}
+
+	if err = c.fac.dropCursorTTL(uid); err != nil {
+		return ErrSelect.WithReason(err).WithDebug(dbg)
+	}
+
+	return nil
+}
+
+// filterQuery - проверка отчета по интервалу дат, статусу и коду или его началу
+func filterQuery(q *Query) orm.Filter {
+	min := time.Unix(0, q.From.UTC().UnixNano())
+	max := time.Unix(0, q.To.UTC().UnixNano())
+	code := []byte(q.Code)
+
+	return func(row fdbx.Pair) (bool, error) {
+		mod := models.GetRootAsFdbxCrash(row.Value(), 0)
+		created := time.Unix(0, mod.Created())
+
+		if created.Before(min) || created.After(max) {
+			return false, nil
+		}
+
+		if status := mod.Status(); status < q.MinStatus || (q.MaxStatus > 0 && status > q.MaxStatus) {
+			return false, nil
+		}
+
+		if q.CodeExact {
+			return bytes.Equal(mod.Code(), code), nil
+		}
+
+		return bytes.HasPrefix(mod.Code(), code), nil
+	}
+}
+
+// dumpQuery - параметры курсора: интервал дат, статусы, порядок, страница, точность кода и сам код
+func dumpQuery(q *Query) []byte {
+	var buf [10]byte
+
+	binary.BigEndian.PutUint16(buf[0:2], q.MinStatus)
+	binary.BigEndian.PutUint16(buf[2:4], q.MaxStatus)
+	binary.BigEndian.PutUint32(buf[4:8], uint32(q.Page))
+	buf[8] = byte(q.Order)
+
+	if q.CodeExact {
+		buf[9] = 1
+	}
+
+	return bytes.Join([][]byte{fdbx.Time2Byte(q.From), fdbx.Time2Byte(q.To), buf[:], []byte(q.Code)}, nil)
+}
+
+func parseQuery(buf []byte) *Query {
+	if len(buf) < 26 {
+		return nil
+	}
+
+	from, _ := fdbx.Byte2Time(buf[0:8])
+	last, _ := fdbx.Byte2Time(buf[8:16])
+
+	return &Query{
+		From:      from,
+		To:        last,
+		MinStatus: binary.BigEndian.Uint16(buf[16:18]),
+		MaxStatus: binary.BigEndian.Uint16(buf[18:20]),
+		Page:      uint(binary.BigEndian.Uint32(buf[20:24])),
+		Order:     Order(buf[24]),
+		CodeExact: buf[25] == 1,
+		Code:      string(buf[26:]),
+	}
+}
+
+func cursorKey(tbid uint16, uid typex.UUID) fdbx.Key {
+	return fdbx.Bytes2Key(append([]byte{byte(tbid >> 8), byte(tbid), nsCursorQuery}, uid...))
+}
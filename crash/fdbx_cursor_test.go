@@ -0,0 +1,36 @@
+package crash
+
+import (
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type FdbxCursorSuite struct {
+	suite.Suite
+}
+
+func (s *FdbxCursorSuite) TestQuery() {
+	now := time.Now()
+
+	q := &Query{
+		From:      time.Unix(0, now.Add(-time.Hour).UnixNano()),
+		To:        time.Unix(0, now.UnixNano()),
+		Order:     OrderAsc,
+		Code:      "test4031",
+		CodeExact: true,
+		MinStatus: 400,
+		MaxStatus: 499,
+		Page:      10,
+	}
+
+	// Параметры курсора сохраняются вместе с точностью кода
+	if res := parseQuery(dumpQuery(q)); s.NotNil(res) {
+		s.True(q.From.Equal(res.From))
+		s.True(q.To.Equal(res.To))
+		res.From, res.To = q.From, q.To
+		s.Equal(q, res)
+	}
+
+	s.Nil(parseQuery(make([]byte, 25)))
+}
@@ -7,9 +7,30 @@ import (
 	"github.com/shestakovda/fdbx/v2"
 	"github.com/shestakovda/fdbx/v2/mvcc"
 	"github.com/shestakovda/fdbx/v2/orm"
+	"github.com/shestakovda/journal/internal/batch"
 	"github.com/shestakovda/typex"
 )
 
+/*
+	Области ключей таблицы отчетов вне областей orm, все в одном месте, чтобы номера не пересекались.
+
+	* Номера выдаются подряд, новые области добавляются только в конец
+	* Изменение номера существующей области теряет уже сохраненные в ней данные
+*/
+const (
+	nsCursorQuery  byte = 0x10 // Параметры курсоров, фильтры orm вместе с запросом не сохраняются
+	nsCursorSearch byte = 0x11 // Параметры и позиция курсоров поиска
+	nsCursorMeta   byte = 0x12 // Время истечения курсора по идентификатору
+	nsCursorTTL    byte = 0x13 // Очередь курсоров по времени истечения
+	nsIssue        byte = 0x14 // Описание и разбор проблемы по отпечатку
+	nsIssueRecent  byte = 0x15 // Отпечатки по времени последнего отчета
+	nsIssueCount   byte = 0x16 // Отпечатки по кол-ву отчетов
+	nsIssueOpen    byte = 0x17 // Отпечатки открытых проблем по сервису и времени последнего отчета
+	nsIssueSeen    byte = 0x18 // Отчеты проблемы по времени создания
+	nsIssueDirty   byte = 0x19 // Отметки отчетов, еще не учтенных в ключах порядка
+	nsIssueSort    byte = 0x1A // Кол-во и время последнего отчета, по которым построены ключи порядка
+)
+
 func newFdbxFactory(tx mvcc.Tx, crashID uint16) *fdbxFactory {
 	return &fdbxFactory{
 		tx:  tx,
@@ -27,13 +48,52 @@ func (f *fdbxFactory) New() Model {
 }
 
 func (f *fdbxFactory) ByID(id string) (_ Model, err error) {
-	var row fdbx.Pair
 	var uid typex.UUID
 
 	if uid, err = typex.ParseUUID(id); err != nil {
 		return nil, ErrIDValidate.WithReason(err)
 	}
 
+	return f.byUID(uid)
+}
+
+func (f *fdbxFactory) ByIDs(ids ...string) (res []Model, miss []string, err error) {
+	var lost []int
+	var uids []typex.UUID
+
+	if uids, err = parseIDs(ids); err != nil {
+		return nil, nil, err
+	}
+
+	mods := make([]Model, len(uids))
+
+	if lost, err = batch.Load(len(uids), func(i int) (_ bool, exp error) {
+		if mods[i], exp = f.byUID(uids[i]); errx.Is(exp, ErrNotFound) {
+			return false, nil
+		}
+		return exp == nil, exp
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	res = make([]Model, 0, len(mods)-len(lost))
+
+	for i := range mods {
+		if mods[i] != nil {
+			res = append(res, mods[i])
+		}
+	}
+
+	for _, i := range lost {
+		miss = append(miss, ids[i])
+	}
+
+	return res, miss, nil
+}
+
+func (f *fdbxFactory) byUID(uid typex.UUID) (_ Model, err error) {
+	var row fdbx.Pair
+
 	if row, err = f.tbl.Select(f.tx).ByID(fdbx.Bytes2Key(uid)).First(); err != nil {
 		dbg := errx.Debug{"ID": uid.Hex()}
 
@@ -50,19 +110,21 @@ func (f *fdbxFactory) ByID(id string) (_ Model, err error) {
 func (f *fdbxFactory) ByDateCode(from, last time.Time, code string) (res []Model, err error) {
 	var rows []fdbx.Pair
 
-	if code != "" {
-		pref := fdbx.String2Key(code)
-		rows, err = f.tbl.Select(f.tx).ByIndexRange(
-			IndexCode,
-			pref.RPart(fdbx.Time2Byte(from)...),
-			pref.RPart(fdbx.Time2Byte(last)...),
-		).All()
-	} else {
-		rows, err = f.tbl.Select(f.tx).ByIndexRange(
-			IndexDate,
-			fdbx.Bytes2Key(fdbx.Time2Byte(from)),
-			fdbx.Bytes2Key(fdbx.Time2Byte(last)),
-		).All()
+	// Код целиком, как и раньше, чтобы интервал дат сужался в самом индексе
+	q := &Query{
+		From:      from,
+		To:        last,
+		Order:     OrderAsc,
+		Code:      code,
+		CodeExact: code != "",
+	}
+
+	if rows, err = f.query(q).Where(filterQuery(q)).All(); err != nil {
+		return nil, ErrSelect.WithReason(err).WithDebug(errx.Debug{
+			"От момента": from.UTC().Format(time.RFC3339Nano),
+			"До момента": last.UTC().Format(time.RFC3339Nano),
+			"По коду":    code,
+		})
 	}
 
 	res = make([]Model, len(rows))
@@ -72,3 +134,121 @@ func (f *fdbxFactory) ByDateCode(from, last time.Time, code string) (res []Model
 
 	return res, nil
 }
+
+func (f *fdbxFactory) Select(q *Query) (_ Cursor, err error) {
+	var qid string
+	var uid typex.UUID
+
+	if q == nil || q.To.Before(q.From) || (q.MaxStatus > 0 && q.MinStatus > q.MaxStatus) {
+		return nil, ErrQueryValidate.WithDetail("Некорректные параметры выборки")
+	}
+
+	if q.Order != OrderDesc && q.Order != OrderAsc {
+		return nil, ErrQueryValidate.WithDetail("Некорректный порядок перебора").WithDebug(errx.Debug{
+			"Порядок": q.Order,
+		})
+	}
+
+	// Курсор хранит свою копию параметров, чтобы их нельзя было изменить снаружи
+	cpy := *q
+
+	if cpy.Page == 0 {
+		cpy.Page = cursorPage
+	}
+
+	que := f.query(&cpy).Page(int(cpy.Page))
+	dbg := errx.Debug{
+		"От момента":      q.From.UTC().Format(time.RFC3339Nano),
+		"До момента":      q.To.UTC().Format(time.RFC3339Nano),
+		"По коду":         q.Code,
+		"Размер страницы": cpy.Page,
+	}
+
+	if qid, err = que.Save(); err != nil {
+		return nil, ErrSelect.WithReason(err).WithDebug(dbg)
+	}
+
+	if uid, err = typex.ParseUUID(qid); err != nil {
+		return nil, ErrSelect.WithReason(err).WithDebug(dbg)
+	}
+
+	if err = f.tx.Upsert([]fdbx.Pair{fdbx.NewPair(cursorKey(f.tbl.ID(), uid), dumpQuery(&cpy))}); err != nil {
+		return nil, ErrSelect.WithReason(err).WithDebug(dbg)
+	}
+
+	if err = f.saveCursorTTL(uid); err != nil {
+		return nil, ErrSelect.WithReason(err).WithDebug(dbg)
+	}
+
+	return newFdbxCursor(f, qid, &cpy, que), nil
+}
+
+func (f *fdbxFactory) Cursor(id string) (_ Cursor, err error) {
+	var cur *fdbxSearchCursor
+
+	// У курсоров поиска свое хранилище позиции
+	if cur, err = loadFdbxSearchCursor(f, id); err != nil {
+		return nil, err
+	}
+
+	if cur != nil {
+		return cur, nil
+	}
+
+	return loadFdbxCursor(f, id)
+}
+
+/*
+	query - выборка по индексу кода, если он указан, иначе по индексу даты.
+
+	* Для кода целиком интервал дат сужается в индексе, как раньше в ByDateCode
+	* После начала кода в ключе сразу идет дата, поэтому для начала кода перебираются все даты
+*/
+func (f *fdbxFactory) query(q *Query) (que orm.Query) {
+	if q.Code != "" && q.CodeExact {
+		pref := fdbx.String2Key(q.Code)
+		que = f.tbl.Select(f.tx).ByIndexRange(
+			IndexCode,
+			pref.RPart(fdbx.Time2Byte(q.From)...),
+			pref.RPart(fdbx.Time2Byte(q.To)...),
+		)
+	} else if q.Code != "" {
+		que = f.tbl.Select(f.tx).ByIndexRange(
+			IndexCode,
+			fdbx.String2Key(q.Code),
+			fdbx.String2Key(q.Code).RPart(0xFF),
+		)
+	} else {
+		que = f.tbl.Select(f.tx).ByIndexRange(
+			IndexDate,
+			fdbx.Bytes2Key(fdbx.Time2Byte(q.From)),
+			fdbx.Bytes2Key(fdbx.Time2Byte(q.To)),
+		)
+	}
+
+	if q.Order == OrderDesc {
+		que = que.Reverse()
+	}
+
+	return que
+}
+
+func (f *fdbxFactory) Search(query string, from, last time.Time, page uint) (_ Cursor, err error) {
+	cur := newFdbxSearchCursor(f, query, from, last, page)
+
+	if len(cur.scan.Words) == 0 {
+		return nil, ErrQueryValidate.WithDebug(errx.Debug{"Запрос": query})
+	}
+
+	// Сохраняем сразу, чтобы курсор был доступен по идентификатору и до загрузки первой страницы
+	if err = cur.save(); err != nil {
+		return nil, ErrSelect.WithReason(err).WithDebug(errx.Debug{
+			"Запрос":          query,
+			"От момента":      from.UTC().Format(time.RFC3339Nano),
+			"До момента":      last.UTC().Format(time.RFC3339Nano),
+			"Размер страницы": page,
+		})
+	}
+
+	return cur, nil
+}
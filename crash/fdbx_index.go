@@ -4,14 +4,39 @@ import (
 	"time"
 
 	"github.com/shestakovda/fdbx/v2"
+	"github.com/shestakovda/journal/internal/search"
 	"github.com/shestakovda/journal/models"
 )
 
 func idxCrash(buf []byte) (map[uint16][]fdbx.Key, error) {
 	mod := models.GetRootAsFdbxCrash(buf, 0)
-	start := fdbx.Bytes2Key(fdbx.Time2Byte(time.Unix(0, mod.Created())))
+	created := fdbx.Time2Byte(time.Unix(0, mod.Created()))
+	start := fdbx.Bytes2Key(created)
+	words := crashTokens(mod)
+	texts := make([]fdbx.Key, len(words))
+
+	for i := range words {
+		texts[i] = fdbx.Bytes2Key(search.Key(words[i], created))
+	}
+
 	return map[uint16][]fdbx.Key{
 		IndexDate: []fdbx.Key{start},
 		IndexCode: []fdbx.Key{start.LPart(mod.Code()...)},
+		IndexText: texts,
 	}, nil
 }
+
+// crashTokens - слова заголовка и текстов шагов ошибки для полнотекстового поиска
+func crashTokens(mod *models.FdbxCrash) []string {
+	stp := new(models.FdbxStep)
+	txt := make([]byte, 0, 256)
+	txt = append(txt, mod.Title()...)
+
+	for i := 0; i < mod.StepsLength(); i++ {
+		if mod.Steps(stp, i) {
+			txt = append(append(txt, ' '), stp.Text()...)
+		}
+	}
+
+	return search.Tokenize(string(txt))
+}
@@ -0,0 +1,530 @@
+package crash
+
+import (
+	"context"
+	"encoding/binary"
+	"strings"
+	"time"
+
+	"github.com/shestakovda/errx"
+	"github.com/shestakovda/fdbx/v2"
+	"github.com/shestakovda/fdbx/v2/db"
+	"github.com/shestakovda/fdbx/v2/mvcc"
+	"github.com/shestakovda/journal/internal/ttl"
+	"github.com/shestakovda/journal/models"
+	"github.com/shestakovda/typex"
+)
+
+// Кол-во отметок новых отчетов, обрабатываемых за одну транзакцию перестроения порядков
+const issuePack = 1000
+
+/*
+	SweepFdbxIssues - периодическое перестроение порядка проблем по отчетам, сохраненным через fdbx/v2.
+
+	* dbc - подключение к БД
+	* crashID - номер таблицы отчетов, как в NewFdbxFactory
+	* wait - интервал между проходами
+	* fail - обработчик ошибок перестроения, может быть пустым
+
+	* Работает до отмены контекста, ошибки не прерывают цикл
+*/
+func SweepFdbxIssues(ctx context.Context, dbc db.Connection, crashID uint16, wait time.Duration, fail func(error)) {
+	ttl.Loop(ctx, wait, func() error {
+		return SortFdbxIssues(dbc, crashID)
+	}, fail)
+}
+
+/*
+	SortFdbxIssues - один проход перестроения порядка проблем, в которые с прошлого раза добавились отчеты.
+
+	* Отметки новых отчетов обрабатываются пачками, каждая пачка в своей транзакции
+	* Кол-во отчетов и ключи порядка меняются вместе с удалением учтенных отметок, поэтому отчеты не теряются
+*/
+func SortFdbxIssues(dbc db.Connection, crashID uint16) (err error) {
+	var tx mvcc.Tx
+
+	for cnt := issuePack; cnt >= issuePack; {
+		if tx, err = mvcc.Begin(dbc); err != nil {
+			return ErrIssueSort.WithReason(err)
+		}
+
+		if cnt, err = newFdbxFactory(tx, crashID).sortIssues(); err != nil {
+			tx.Cancel()
+			return ErrIssueSort.WithReason(err)
+		}
+
+		if err = tx.Commit(); err != nil {
+			return ErrIssueSort.WithReason(err)
+		}
+	}
+
+	if tx, err = mvcc.Begin(dbc); err != nil {
+		return ErrIssueSort.WithReason(err)
+	}
+	defer tx.Cancel()
+
+	// Удаленные отметки остаются в БД до очистки старых версий
+	if err = tx.Vacuum(issueKey(crashID, nsIssueDirty, nil, "")); err != nil {
+		return ErrIssueSort.WithReason(err)
+	}
+
+	return nil
+}
+
+func (f *fdbxFactory) Issue(fingerprint string) (_ *Issue, err error) {
+	var iss *Issue
+
+	dbg := errx.Debug{"Отпечаток": fingerprint}
+
+	if iss, err = f.loadIssue(fingerprint); err != nil {
+		return nil, ErrSelect.WithReason(err).WithDebug(dbg)
+	}
+
+	if iss == nil {
+		return nil, ErrNotFound.WithDebug(dbg)
+	}
+
+	if err = f.fillIssue(iss); err != nil {
+		return nil, ErrSelect.WithReason(err).WithDebug(dbg)
+	}
+
+	return iss, nil
+}
+
+func (f *fdbxFactory) Issues(ord IssueOrder, limit uint) ([]*Issue, error) {
+	ns := nsIssueRecent
+
+	if ord == IssueByCount {
+		ns = nsIssueCount
+	}
+
+	return f.listIssues(issueKey(f.tbl.ID(), ns, nil, ""), limit, errx.Debug{"Порядок": ord, "Кол-во": limit})
+}
+
+func (f *fdbxFactory) OpenIssues(service string, limit uint) ([]*Issue, error) {
+	pref := issueKey(f.tbl.ID(), nsIssueOpen, issueService(service), "")
+	return f.listIssues(pref, limit, errx.Debug{"Сервис": service, "Кол-во": limit})
+}
+
+// listIssues - проблемы по ключам порядка с префиксом, в конце ключа 8 байт порядка и отпечаток
+func (f *fdbxFactory) listIssues(pref fdbx.Key, limit uint, dbg errx.Debug) (res []*Issue, err error) {
+	var iss *Issue
+	var keys []fdbx.Pair
+
+	if keys, err = f.tx.ListAll(
+		mvcc.From(pref),
+		mvcc.Last(pref.RPart(0xFF)),
+		mvcc.Limit(int(limit)),
+		mvcc.Reverse(),
+	); err != nil {
+		return nil, ErrSelect.WithReason(err).WithDebug(dbg)
+	}
+
+	res = make([]*Issue, 0, len(keys))
+	size := len(pref.Bytes()) + 8
+
+	for i := range keys {
+		if buf := keys[i].Key().Bytes(); len(buf) > size {
+			if iss, err = f.loadIssue(string(buf[size:])); err != nil {
+				return nil, ErrSelect.WithReason(err).WithDebug(dbg)
+			}
+
+			if iss == nil {
+				continue
+			}
+
+			if err = f.fillIssue(iss); err != nil {
+				return nil, ErrSelect.WithReason(err).WithDebug(dbg)
+			}
+
+			res = append(res, iss)
+		}
+	}
+
+	return res, nil
+}
+
+func (f *fdbxFactory) SetIssueState(fingerprint string, state IssueState, user, note string) (*Issue, error) {
+	return f.updateIssue(fingerprint, func(iss *Issue) error {
+		return iss.setState(state, user, note, time.Now().UTC())
+	})
+}
+
+func (f *fdbxFactory) AssignIssue(fingerprint, owner, user string) (*Issue, error) {
+	return f.updateIssue(fingerprint, func(iss *Issue) error {
+		iss.assign(owner, user, time.Now().UTC())
+		return nil
+	})
+}
+
+func (f *fdbxFactory) NoteIssue(fingerprint, user, note string) (*Issue, error) {
+	return f.updateIssue(fingerprint, func(iss *Issue) error {
+		return iss.note(user, note, time.Now().UTC())
+	})
+}
+
+// updateIssue - изменение разбора существующей проблемы вместе с ее ключами порядка
+func (f *fdbxFactory) updateIssue(fp string, hdl func(*Issue) error) (iss *Issue, err error) {
+	dbg := errx.Debug{"Отпечаток": fp}
+
+	if iss, err = f.loadIssue(fp); err != nil {
+		return nil, ErrSelect.WithReason(err).WithDebug(dbg)
+	}
+
+	if iss == nil {
+		return nil, ErrNotFound.WithDebug(dbg)
+	}
+
+	if err = f.loadSort(iss); err != nil {
+		return nil, ErrSelect.WithReason(err).WithDebug(dbg)
+	}
+
+	old := issueOrderKeys(f.tbl.ID(), iss)
+
+	if err = hdl(iss); err != nil {
+		return nil, err
+	}
+
+	if err = f.saveIssue(iss); err != nil {
+		return nil, ErrInsert.WithReason(err).WithDebug(dbg)
+	}
+
+	if err = f.saveSort(iss, old); err != nil {
+		return nil, ErrInsert.WithReason(err).WithDebug(dbg)
+	}
+
+	if err = f.fillIssue(iss); err != nil {
+		return nil, ErrSelect.WithReason(err).WithDebug(dbg)
+	}
+
+	return iss, nil
+}
+
+/*
+	addIssue - учет отчета в его проблеме.
+
+	* Отчет и отметка о нем пишутся в свои ключи, поэтому одинаковые отчеты не конфликтуют
+	* Описание проблемы только читается, а перезаписывается при ее появлении и при повторе исправленной
+	* Кол-во отчетов и ключи порядка перестраиваются по отметкам в SortFdbxIssues
+*/
+func (f *fdbxFactory) addIssue(r *Report, uid typex.UUID) (err error) {
+	var iss *Issue
+
+	fp := r.Fingerprint()
+	tbid := f.tbl.ID()
+
+	if iss, err = f.loadIssue(fp); err != nil {
+		return err
+	}
+
+	if iss == nil || iss.State == IssueResolved {
+		if iss == nil {
+			iss = &Issue{Fingerprint: fp}
+		}
+
+		iss.add(r)
+
+		if err = f.saveIssue(iss); err != nil {
+			return err
+		}
+	}
+
+	created := fdbx.Time2Byte(r.Created)
+
+	return f.tx.Upsert([]fdbx.Pair{
+		fdbx.NewPair(issueKey(tbid, nsIssueSeen, nil, fp).RPart(append(created, uid...)...), nil),
+		fdbx.NewPair(issueKey(tbid, nsIssueDirty, nil, fp).RPart(uid...), created),
+	})
+}
+
+// issueDirty - отчеты проблемы, еще не учтенные в ключах порядка
+type issueDirty struct {
+	count uint64
+	last  time.Time
+}
+
+/*
+	sortIssues - перестроение ключей порядка по пачке отметок новых отчетов.
+
+	* Возвращает кол-во обработанных отметок, если их меньше issuePack, то других пока нет
+*/
+func (f *fdbxFactory) sortIssues() (_ int, err error) {
+	var rows []fdbx.Pair
+
+	pref := issueKey(f.tbl.ID(), nsIssueDirty, nil, "")
+
+	if rows, err = f.tx.ListAll(mvcc.From(pref), mvcc.Last(pref), mvcc.Limit(issuePack)); err != nil {
+		return 0, err
+	}
+
+	fps, dirty := groupDirty(rows, len(pref.Bytes()))
+
+	for _, fp := range fps {
+		if err = f.sortIssue(fp, dirty[fp]); err != nil {
+			return 0, err
+		}
+	}
+
+	keys := make([]fdbx.Key, len(rows))
+
+	for i := range rows {
+		keys[i] = rows[i].Key()
+	}
+
+	if err = f.tx.Delete(keys); err != nil {
+		return 0, err
+	}
+
+	return len(rows), nil
+}
+
+// groupDirty - отметки по отпечаткам в порядке их появления, в конце ключа отметки идентификатор отчета
+func groupDirty(rows []fdbx.Pair, size int) (fps []string, res map[string]*issueDirty) {
+	res = make(map[string]*issueDirty)
+
+	for i := range rows {
+		buf := rows[i].Key().Bytes()
+
+		if len(buf) <= size+16 {
+			continue
+		}
+
+		fp := string(buf[size : len(buf)-16])
+		dty := res[fp]
+
+		if dty == nil {
+			dty = new(issueDirty)
+			res[fp] = dty
+			fps = append(fps, fp)
+		}
+
+		dty.count++
+
+		if created, err := fdbx.Byte2Time(rows[i].Value()); err == nil && created.After(dty.last) {
+			dty.last = created
+		}
+	}
+
+	return fps, res
+}
+
+// sortIssue - замена ключей порядка проблемы на построенные с учетом новых отчетов
+func (f *fdbxFactory) sortIssue(fp string, dty *issueDirty) (err error) {
+	var iss *Issue
+
+	if iss, err = f.loadIssue(fp); err != nil || iss == nil {
+		return err
+	}
+
+	if err = f.loadSort(iss); err != nil {
+		return err
+	}
+
+	old := issueOrderKeys(f.tbl.ID(), iss)
+
+	if iss.Count += dty.count; dty.last.After(iss.LastSeen) {
+		iss.LastSeen = dty.last
+	}
+
+	return f.saveSort(iss, old)
+}
+
+// saveIssue - сохранение описания и разбора проблемы
+func (f *fdbxFactory) saveIssue(iss *Issue) error {
+	key := issueKey(f.tbl.ID(), nsIssue, nil, iss.Fingerprint)
+	return f.tx.Upsert([]fdbx.Pair{fdbx.NewPair(key, fdbx.FlatPack(iss.dump()))})
+}
+
+// loadIssue - описание и разбор проблемы по отпечатку, если ее нет, то nil
+func (f *fdbxFactory) loadIssue(fp string) (_ *Issue, err error) {
+	var row fdbx.Pair
+
+	if row, err = f.tx.Select(issueKey(f.tbl.ID(), nsIssue, nil, fp)); err != nil {
+		if errx.Is(err, mvcc.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return fdbxLoadIssue(models.GetRootAsFdbxIssue(row.Value(), 0).UnPack()), nil
+}
+
+// saveSort - замена старых ключей порядка построенными по кол-ву и последнему отчету проблемы
+func (f *fdbxFactory) saveSort(iss *Issue, old []fdbx.Key) (err error) {
+	tbid := f.tbl.ID()
+
+	if err = f.tx.Delete(old); err != nil {
+		return err
+	}
+
+	keys := issueOrderKeys(tbid, iss)
+	rows := make([]fdbx.Pair, 0, len(keys)+1)
+	rows = append(rows, fdbx.NewPair(
+		issueKey(tbid, nsIssueSort, nil, iss.Fingerprint),
+		append(issueCount(iss.Count), fdbx.Time2Byte(iss.LastSeen)...),
+	))
+
+	for i := range keys {
+		rows = append(rows, fdbx.NewPair(keys[i], nil))
+	}
+
+	return f.tx.Upsert(rows)
+}
+
+// loadSort - кол-во и время последнего отчета, по которым построены текущие ключи порядка проблемы
+func (f *fdbxFactory) loadSort(iss *Issue) (err error) {
+	var row fdbx.Pair
+
+	if row, err = f.tx.Select(issueKey(f.tbl.ID(), nsIssueSort, nil, iss.Fingerprint)); err != nil {
+		if errx.Is(err, mvcc.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if buf := row.Value(); len(buf) == 16 {
+		iss.Count = binary.BigEndian.Uint64(buf[:8])
+		iss.LastSeen, _ = fdbx.Byte2Time(buf[8:])
+	}
+
+	return nil
+}
+
+/*
+	fillIssue - кол-во, время первого и последнего отчета и последние отчеты проблемы.
+
+	* Кол-во берется из ключей порядка вместе с еще не учтенными в них отчетами
+*/
+func (f *fdbxFactory) fillIssue(iss *Issue) (err error) {
+	var rows []fdbx.Pair
+
+	tbid := f.tbl.ID()
+	pref := issueKey(tbid, nsIssueSeen, nil, iss.Fingerprint)
+	dirty := issueKey(tbid, nsIssueDirty, nil, iss.Fingerprint)
+	size := len(pref.Bytes())
+
+	iss.Count = 0
+	iss.Samples = nil
+
+	if err = f.loadSort(iss); err != nil {
+		return err
+	}
+
+	if rows, err = f.tx.ListAll(mvcc.From(dirty), mvcc.Last(dirty)); err != nil {
+		return err
+	}
+
+	iss.Count += uint64(len(rows))
+
+	// В конце ключа время создания и идентификатор отчета
+	if rows, err = f.tx.ListAll(mvcc.From(pref), mvcc.Last(pref), mvcc.Limit(1)); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if buf := row.Key().Bytes(); len(buf) == size+24 {
+			iss.FirstSeen, _ = fdbx.Byte2Time(buf[size : size+8])
+		}
+	}
+
+	if rows, err = f.tx.ListAll(mvcc.From(pref), mvcc.Last(pref), mvcc.Limit(IssueSamples), mvcc.Reverse()); err != nil {
+		return err
+	}
+
+	for i, row := range rows {
+		if buf := row.Key().Bytes(); len(buf) == size+24 {
+			if i == 0 {
+				iss.LastSeen, _ = fdbx.Byte2Time(buf[size : size+8])
+			}
+			iss.Samples = append(iss.Samples, typex.UUID(buf[size+8:]).Hex())
+		}
+	}
+
+	return nil
+}
+
+func (i *Issue) dump() *models.FdbxIssueT {
+	obj := &models.FdbxIssueT{
+		Fingerprint: i.Fingerprint,
+		Service:     i.Service,
+		Code:        i.Code,
+		Title:       i.Title,
+		Status:      i.Status,
+		State:       byte(i.State),
+		Owner:       i.Owner,
+		History:     make([]*models.FdbxIssueEventT, len(i.History)),
+	}
+
+	for j, e := range i.History {
+		obj.History[j] = &models.FdbxIssueEventT{
+			Time:  e.Time.UTC().UnixNano(),
+			User:  e.User,
+			From:  byte(e.From),
+			To:    byte(e.To),
+			Owner: e.Owner,
+			Note:  e.Note,
+		}
+	}
+
+	return obj
+}
+
+func fdbxLoadIssue(obj *models.FdbxIssueT) *Issue {
+	iss := &Issue{
+		Fingerprint: obj.Fingerprint,
+		Service:     obj.Service,
+		Code:        obj.Code,
+		Title:       obj.Title,
+		Status:      obj.Status,
+		State:       IssueState(obj.State),
+		Owner:       obj.Owner,
+	}
+
+	if len(obj.History) > 0 {
+		iss.History = make([]*IssueEvent, len(obj.History))
+	}
+
+	for j, e := range obj.History {
+		iss.History[j] = &IssueEvent{
+			Time:  time.Unix(0, e.Time).UTC(),
+			User:  e.User,
+			From:  IssueState(e.From),
+			To:    IssueState(e.To),
+			Owner: e.Owner,
+			Note:  e.Note,
+		}
+	}
+
+	return iss
+}
+
+// issueKey - ключ области проблем, с порядковой частью и отпечатком
+func issueKey(tbid uint16, ns byte, ord []byte, fp string) fdbx.Key {
+	return fdbx.Bytes2Key([]byte{byte(tbid >> 8), byte(tbid), ns}).RPart(ord...).RPart([]byte(fp)...)
+}
+
+// issueOrderKeys - ключи проблемы во всех порядках, открытые еще и в порядке своего сервиса
+func issueOrderKeys(tbid uint16, iss *Issue) []fdbx.Key {
+	last := fdbx.Time2Byte(iss.LastSeen)
+	keys := []fdbx.Key{
+		issueKey(tbid, nsIssueRecent, last, iss.Fingerprint),
+		issueKey(tbid, nsIssueCount, issueCount(iss.Count), iss.Fingerprint),
+	}
+
+	if iss.State.Open() {
+		keys = append(keys, issueKey(tbid, nsIssueOpen, append(issueService(iss.Service), last...), iss.Fingerprint))
+	}
+
+	return keys
+}
+
+// issueService - часть ключа с сервисом и разделителем, чтобы сервисы не были префиксами друг друга
+func issueService(srv string) []byte {
+	return append([]byte(strings.ToLower(srv)), 0)
+}
+
+func issueCount(cnt uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, cnt)
+	return buf
+}
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/shestakovda/errx"
 	"github.com/shestakovda/fdbx/v2"
 	"github.com/shestakovda/journal/models"
 	"github.com/shestakovda/typex"
@@ -26,6 +27,8 @@ func loadFdbxModel(fac *fdbxFactory, uid typex.UUID, buf []byte) *fdbxModel {
 		title:   obj.Title,
 		status:  obj.Status,
 		created: time.Unix(0, obj.Created).UTC(),
+		service: obj.Service,
+		trace:   obj.Trace,
 		steps:   make([]*fdbxStep, len(obj.Steps)),
 
 		fac: fac,
@@ -45,6 +48,8 @@ type fdbxModel struct {
 	title   string
 	status  uint16
 	created time.Time
+	service string
+	trace   string
 	steps   []*fdbxStep
 
 	fac *fdbxFactory
@@ -60,13 +65,23 @@ func (m *fdbxModel) Import(r *Report) (err error) {
 	m.title = r.Title
 	m.status = r.Status
 	m.created = r.Created.UTC()
+	m.service = r.Service
+	m.trace = r.Trace
 	m.steps = make([]*fdbxStep, len(r.Entries))
 
 	for i := range r.Entries {
 		m.steps[i] = fdbxNewStep(r.Entries[i])
 	}
 
-	return m.save()
+	if err = m.save(); err != nil {
+		return err
+	}
+
+	if err = m.fac.addIssue(r, m.uid); err != nil {
+		return ErrInsert.WithReason(err).WithDebug(errx.Debug{"ID": r.ID})
+	}
+
+	return nil
 }
 
 func (m *fdbxModel) Export() *Report {
@@ -78,12 +93,16 @@ func (m *fdbxModel) Export() *Report {
 		Status:  m.status,
 		Created: m.created,
 		Entries: make([]*ReportEntry, len(m.steps)),
+		Service: m.service,
+		Trace:   m.trace,
 	}
 
 	for i := range m.steps {
 		res.Entries[i] = m.steps[i].Export()
 	}
 
+	res.Params = invalidParams(nil, res.Entries)
+
 	return res
 }
 
@@ -113,12 +132,24 @@ func (m *fdbxModel) ExportRFC() *RFC {
 		Title:   m.title,
 		Status:  m.status,
 		Created: m.created.Format(time.RFC3339Nano),
+		Trace:   m.trace,
 	}
 
 	if res.Link == "" {
 		res.Link = BlankLink
 	}
 
+	// Некорректные параметры хранятся в отладке записей, как и в отчете - только для статусов ниже 500
+	if m.status < 500 {
+		entries := make([]*ReportEntry, len(m.steps))
+
+		for i := range m.steps {
+			entries[i] = m.steps[i].Export()
+		}
+
+		res.Params = invalidParams(nil, entries)
+	}
+
 	parts := make([]string, 0, len(m.steps))
 	for i := len(m.steps) - 1; i >= 0; i-- {
 		if m.steps[i].detail != "" {
@@ -137,6 +168,8 @@ func (m *fdbxModel) save() (err error) {
 		Title:   m.title,
 		Status:  m.status,
 		Created: m.created.UTC().UnixNano(),
+		Service: m.service,
+		Trace:   m.trace,
 		Steps:   make([]*models.FdbxStepT, len(m.steps)),
 	}
 
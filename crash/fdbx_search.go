@@ -0,0 +1,187 @@
+package crash
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	"github.com/shestakovda/errx"
+	"github.com/shestakovda/fdbx/v2"
+	"github.com/shestakovda/fdbx/v2/mvcc"
+	"github.com/shestakovda/journal/internal/search"
+	"github.com/shestakovda/journal/models"
+	"github.com/shestakovda/typex"
+)
+
+func newFdbxSearchCursor(fac *fdbxFactory, query string, from, last time.Time, page uint) *fdbxSearchCursor {
+	if page == 0 {
+		page = cursorPage
+	}
+
+	return &fdbxSearchCursor{
+		fac:  fac,
+		page: page,
+		text: query,
+		scan: search.Scan{
+			Index:  IndexText,
+			Words:  search.Tokenize(query),
+			From:   time.Unix(0, from.UTC().UnixNano()),
+			Last:   time.Unix(0, last.UTC().UnixNano()),
+			Tokens: func(val []byte) []string { return crashTokens(models.GetRootAsFdbxCrash(val, 0)) },
+		},
+	}
+}
+
+// loadFdbxSearchCursor - загрузка курсора поиска, если под этим идентификатором сохранен обычный курсор, то nil
+func loadFdbxSearchCursor(fac *fdbxFactory, qid string) (_ *fdbxSearchCursor, err error) {
+	var row fdbx.Pair
+	var uid typex.UUID
+
+	dbg := errx.Debug{"Курсор": qid}
+
+	if uid, err = typex.ParseUUID(qid); err != nil {
+		return nil, ErrIDValidate.WithReason(err).WithDebug(dbg)
+	}
+
+	if row, err = fac.tx.Select(searchKey(fac.tbl.ID(), uid)); err != nil {
+		if errx.Is(err, mvcc.ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, ErrSelect.WithReason(err).WithDebug(dbg)
+	}
+
+	if err = fac.checkCursorTTL(uid); err != nil {
+		return nil, err
+	}
+
+	// Время начала и конца интервала, размер страницы, длина позиции, позиция и сам запрос
+	buf := row.Value()
+
+	if len(buf) < 22 || len(buf) < 22+int(binary.BigEndian.Uint16(buf[20:22])) {
+		return nil, ErrSelect.WithDetail("Некорректные параметры поиска").WithDebug(dbg)
+	}
+
+	from, _ := fdbx.Byte2Time(buf[0:8])
+	last, _ := fdbx.Byte2Time(buf[8:16])
+	size := 22 + int(binary.BigEndian.Uint16(buf[20:22]))
+
+	cur := newFdbxSearchCursor(fac, string(buf[size:]), from, last, uint(binary.BigEndian.Uint32(buf[16:20])))
+	cur.qid = qid
+
+	if size > 22 {
+		cur.pos = buf[22:size]
+	}
+
+	return cur, nil
+}
+
+// fdbxSearchCursor - перебор по индексу слов, сохраняется в БД вместе с запросом и позицией
+type fdbxSearchCursor struct {
+	empty bool
+
+	qid  string
+	pos  []byte
+	page uint
+	text string
+	scan search.Scan
+	fac  *fdbxFactory
+}
+
+func (c *fdbxSearchCursor) ID() string {
+	return c.qid
+}
+
+func (c *fdbxSearchCursor) Empty() bool {
+	return c.empty
+}
+
+func (c *fdbxSearchCursor) NextPage(size uint) (res []Model, err error) {
+	if c.empty {
+		return nil, nil
+	}
+
+	if size == 0 {
+		size = c.page
+	}
+
+	dbg := errx.Debug{
+		"Курсор":          c.qid,
+		"Размер страницы": size,
+	}
+
+	if res, err = c.next(int(size)); err != nil {
+		return nil, ErrSelect.WithReason(err).WithDebug(dbg)
+	}
+
+	if err = c.save(); err != nil {
+		return nil, ErrSelect.WithReason(err).WithDebug(dbg)
+	}
+
+	return res, nil
+}
+
+func (c *fdbxSearchCursor) Close() (err error) {
+	var uid typex.UUID
+
+	c.empty = true
+	dbg := errx.Debug{"Курсор": c.qid}
+
+	if uid, err = typex.ParseUUID(c.qid); err != nil {
+		return ErrIDValidate.WithReason(err).WithDebug(dbg)
+	}
+
+	if err = c.fac.dropCursorTTL(uid); err != nil {
+		return ErrSelect.WithReason(err).WithDebug(dbg)
+	}
+
+	return nil
+}
+
+// next - следующие отчеты по индексу слов, позиция и признак конца перебора остаются в курсоре
+func (c *fdbxSearchCursor) next(size int) (res []Model, err error) {
+	var pos []byte
+	var done bool
+	var rows []fdbx.Pair
+
+	if rows, pos, done, err = c.scan.Next(c.fac.tx, c.fac.tbl, c.pos, size); err != nil {
+		return nil, err
+	}
+
+	c.pos = pos
+	c.empty = done
+
+	res = make([]Model, len(rows))
+	for i := range rows {
+		res[i] = loadFdbxModel(c.fac, typex.UUID(rows[i].Key().Bytes()), rows[i].Value())
+	}
+
+	return res, nil
+}
+
+// save - сохранение запроса и текущей позиции, новому курсору выдается идентификатор и срок действия
+func (c *fdbxSearchCursor) save() (err error) {
+	var uid typex.UUID
+
+	if c.qid == "" {
+		uid = typex.NewUUID()
+		c.qid = uid.Hex()
+
+		if err = c.fac.saveCursorTTL(uid); err != nil {
+			return err
+		}
+	} else if uid, err = typex.ParseUUID(c.qid); err != nil {
+		return err
+	}
+
+	var buf [6]byte
+	binary.BigEndian.PutUint32(buf[0:4], uint32(c.page))
+	binary.BigEndian.PutUint16(buf[4:6], uint16(len(c.pos)))
+
+	val := bytes.Join([][]byte{fdbx.Time2Byte(c.scan.From), fdbx.Time2Byte(c.scan.Last), buf[:], c.pos, []byte(c.text)}, nil)
+	return c.fac.tx.Upsert([]fdbx.Pair{fdbx.NewPair(searchKey(c.fac.tbl.ID(), uid), val)})
+}
+
+func searchKey(tbid uint16, uid typex.UUID) fdbx.Key {
+	return fdbx.Bytes2Key(append([]byte{byte(tbid >> 8), byte(tbid), nsCursorSearch}, uid...))
+}
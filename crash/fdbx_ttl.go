@@ -0,0 +1,79 @@
+package crash
+
+import (
+	"context"
+	"time"
+
+	"github.com/shestakovda/errx"
+	"github.com/shestakovda/fdbx/v2"
+	"github.com/shestakovda/fdbx/v2/db"
+	"github.com/shestakovda/fdbx/v2/orm"
+	"github.com/shestakovda/journal/internal/ttl"
+	"github.com/shestakovda/typex"
+)
+
+/*
+	SweepFdbxCursors - периодическое удаление истекших курсоров отчетов, сохраненных через fdbx/v2.
+
+	* dbc - подключение к БД
+	* crashID - номер таблицы отчетов, как в NewFdbxFactory
+	* wait - интервал между проходами очистки
+	* fail - обработчик ошибок очистки, может быть пустым
+
+	* Работает до отмены контекста, ошибки очистки не прерывают цикл
+*/
+func SweepFdbxCursors(ctx context.Context, dbc db.Connection, crashID uint16, wait time.Duration, fail func(error)) {
+	ttl.Loop(ctx, wait, func() error {
+		return sweepFdbxCursors(dbc, crashID, time.Now())
+	}, fail)
+}
+
+// sweepFdbxCursors - один проход очистки истекших курсоров на момент now
+func sweepFdbxCursors(dbc db.Connection, tbid uint16, now time.Time) error {
+	if err := cursorSpace(tbid).Sweep(dbc, now, CursorGrace); err != nil {
+		return ErrSweep.WithReason(err)
+	}
+
+	return nil
+}
+
+// saveCursorTTL - сохранение срока действия нового курсора
+func (f *fdbxFactory) saveCursorTTL(uid typex.UUID) error {
+	return cursorSpace(f.tbl.ID()).Save(f.tx, uid, time.Now().Add(CursorTTL))
+}
+
+// checkCursorTTL - проверка срока действия курсора, у курсоров без срока он не ограничен
+func (f *fdbxFactory) checkCursorTTL(uid typex.UUID) (err error) {
+	var exp time.Time
+
+	if exp, err = cursorSpace(f.tbl.ID()).Expire(f.tx, uid); err != nil {
+		return ErrSelect.WithReason(err)
+	}
+
+	if !exp.IsZero() && exp.Before(time.Now()) {
+		return ErrExpired.WithDebug(errx.Debug{
+			"Курсор":  uid.Hex(),
+			"Истек в": exp.UTC().Format(time.RFC3339Nano),
+		})
+	}
+
+	return nil
+}
+
+// dropCursorTTL - удаление параметров закрытого курсора вместе со сведениями о сроке действия
+func (f *fdbxFactory) dropCursorTTL(uid typex.UUID) error {
+	return cursorSpace(f.tbl.ID()).Drop(f.tx, uid)
+}
+
+// cursorSpace - области учета срока жизни курсоров таблицы отчетов, по истечении удаляются запрос и параметры
+func cursorSpace(tbid uint16) *ttl.Space {
+	return &ttl.Space{
+		Table:  tbid,
+		Meta:   nsCursorMeta,
+		Queue:  nsCursorTTL,
+		Vacuum: []byte{nsCursorQuery, nsCursorSearch},
+		Data: func(uid typex.UUID) []fdbx.Key {
+			return []fdbx.Key{orm.WrapQueryKey(tbid, fdbx.Bytes2Key(uid)), cursorKey(tbid, uid), searchKey(tbid, uid)}
+		},
+	}
+}
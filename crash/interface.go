@@ -10,8 +10,48 @@ import (
 const (
 	IndexDate uint16 = 0x0001
 	IndexCode uint16 = 0x0002
+	IndexText uint16 = 0x0003
 )
 
+// Order - порядок перебора отчетов в курсоре
+type Order uint8
+
+// Допустимые порядки перебора
+const (
+	OrderDesc Order = 0 // Сначала новые отчеты, по-умолчанию
+	OrderAsc  Order = 1 // Сначала старые отчеты
+)
+
+// Сроки жизни сохраненных курсоров, как у курсоров журнала
+var (
+	CursorTTL   = 24 * time.Hour // Через сколько после создания курсор считается истекшим
+	CursorGrace = 24 * time.Hour // Сколько после удаления истекшего курсора о нем помнить
+)
+
+/*
+	Query - параметры выборки отчетов об ошибках для курсора.
+
+	* From, To - интервал времени создания отчетов
+	* Order - порядок перебора, по-умолчанию сначала новые
+	* Code - начало кода ошибки, если пустой, то любой код
+	* CodeExact - Code указан целиком, тогда интервал дат сужается в самом индексе кода
+	* MinStatus, MaxStatus - интервал http статусов включительно, 0 - без ограничения
+	* Page - размер страницы по-умолчанию
+
+	* Если указано начало кода, отчеты упорядочены сначала по коду, затем по дате
+	* По началу кода индекс перебирается за все время, даты проверяются уже по отчетам
+*/
+type Query struct {
+	From      time.Time
+	To        time.Time
+	Order     Order
+	Code      string
+	CodeExact bool
+	MinStatus uint16
+	MaxStatus uint16
+	Page      uint
+}
+
 func NewFdbxFactory(tx mvcc.Tx, crashID uint16) Factory { return newFdbxFactory(tx, crashID) }
 
 /*
@@ -31,15 +71,80 @@ type Provider interface {
 
 		* В случае, если передан некорректный параметр, паникует
 		* Может вызываться несколько раз. Если у двух разных ошибок указана одинаковая внутренняя, сработает первая
+		* Равносильно RegisterRule с нулевым приоритетом
 	*/
 	Register(status, number int, title string, triggers ...error)
 
+	/*
+		RegisterTitles - то же, что Register, но с заголовками на разных языках.
+
+		* titles - заголовки по тегам языков, заголовок на языке DefaultLang обязателен
+	*/
+	RegisterTitles(status, number int, titles map[string]string, triggers ...error)
+
+	/*
+		RegisterRule - регистрирует соответствие внешней ошибки по правилу.
+
+		* Код ошибки формируется так же, как в Register: сервис, статус и номер
+		* Правила проверяются по убыванию приоритета, при равном приоритете - в порядке регистрации
+
+		* В случае, если передан некорректный параметр, паникует
+	*/
+	RegisterRule(rule *Rule)
+
+	/*
+		SetFallback - правило для ошибок, которым не подошло ни одно зарегистрированное.
+
+		* Используются Status, Number, Title, Titles, Link и Detail, условия срабатывания не нужны
+		* Если rule пустое, то снова используется правило по-умолчанию: статус 500, номер 9 и заголовок UnknownErrMsg
+
+		* В случае, если передан некорректный параметр, паникует
+	*/
+	SetFallback(rule *Rule)
+
 	/*
 		Report - формирование новой внешней ошибки.
 
 		* err - внутренняя ошибка, для поиска соответствующей внешней. В случае пустого err возвращает nil
+		* accept - значение Accept-Language, по нему выбирается язык заголовка, если не указан - DefaultLang
+	*/
+	Report(err error, accept ...string) *Report
+
+	/*
+		Catalog - перечень всех кодов ошибок, которые может сформировать Report.
+
+		* Включает все зарегистрированные правила и код по-умолчанию для незарегистрированных ошибок
+		* Это снимок на момент вызова, последующие регистрации в нем не отражаются
 	*/
-	Report(err error) *Report
+	Catalog() *Catalog
+}
+
+/*
+	Rule - правило соответствия внутренних ошибок внешней.
+
+	* Status, Number, Title - как в Provider.Register
+	* Titles - переводы заголовка по тегам языков, Title используется, если подходящего перевода нет
+	* Link - шаблон ссылки на документацию с подстановками {code}, {status} и {number}, по-умолчанию адрес провайдера и код
+	* Priority - чем больше, тем раньше проверяется правило
+	* Triggers - внутренние ошибки, проверяются через errx.Is
+	* Match - предикат, получает ошибку целиком, без разворачивания цепочки
+	* Target - указатель на переменную типа ошибки, проверяется через errors.As, например new(*os.PathError)
+	* Detail - текст детализации отчета из ошибки, если пустой, то детализация остается как есть
+
+	* Должен быть указан хотя бы один из Triggers, Match или Target, срабатывает любой из них
+	* Если сработал Target, то в Detail передается найденная ошибка этого типа, иначе ошибка целиком
+*/
+type Rule struct {
+	Status   int
+	Number   int
+	Title    string
+	Titles   map[string]string
+	Link     string
+	Priority int
+	Triggers []error
+	Match    func(error) bool
+	Target   interface{}
+	Detail   func(error) string
 }
 
 // Factory - поставщик моделей для работы в рамках транзакции
@@ -59,12 +164,146 @@ type Factory interface {
 	*/
 	ByID(id string) (Model, error)
 
+	/*
+		ByIDs - получение отчетов об ошибках по списку идентификаторов.
+
+		* ids не должны быть пустыми и валидируются как core.UUID, иначе ErrIDValidate
+
+		* Найденные отчеты возвращаются в порядке ids, ненайденные идентификаторы - отдельным списком
+		* Если что-то пошло не так, ErrSelect
+	*/
+	ByIDs(ids ...string) (res []Model, miss []string, err error)
+
 	/*
 		ByDateCode - список ошибок по диапазону дат и коду (или его части)
 
 		* Если не указывать код, тогда фильтрация только по дате
+		* Если что-то пошло не так, ErrSelect
+
+		Deprecated: загружает сразу весь интервал, вместо него нужно использовать Select с постраничной загрузкой
 	*/
 	ByDateCode(from, to time.Time, code string) ([]Model, error)
+
+	/*
+		Select - формирование курсора постраничного перебора отчетов.
+
+		* q - интервал дат, порядок и фильтры по коду и статусу
+
+		* Курсор сохраняется в БД вместе с фильтрами, его можно загрузить через Cursor
+		* Через CursorTTL курсор истекает, удаляются истекшие курсоры через SweepFdbxCursors
+		* Если параметры некорректные или порядок неизвестный, ErrQueryValidate
+		* Если реализация не поддерживает курсоры, ErrNotSupported
+		* Если что-то пошло не так, ErrSelect
+	*/
+	Select(q *Query) (Cursor, error)
+
+	/*
+		Cursor - загрузка сохраненного курсора, в том числе курсора поиска.
+
+		* Если не найден, ErrNotFound
+		* Если истек срок действия, ErrExpired, он тоже считается ErrNotFound
+		* Если реализация не поддерживает курсоры, ErrNotSupported
+		* Если что-то пошло не так, ErrSelect
+	*/
+	Cursor(id string) (Cursor, error)
+
+	/*
+		Search - полнотекстовый поиск по заголовкам и текстам шагов ошибок в диапазоне дат.
+
+		* query - слова через пробел, каждое должно быть началом какого-то слова отчета
+		* Регистр не важен, поддерживаются любые алфавиты
+		* page - размер страницы по умолчанию, 0 - как в Select
+
+		* Курсор сохраняется в БД вместе с запросом и позицией, его можно загрузить через Cursor
+		* Срок действия курсора как при вызове Select
+		* Отчеты упорядочены по словам индекса, а не по времени
+		* Если в запросе нет ни одного слова, ErrQueryValidate
+		* Если реализация не поддерживает поиск, ErrNotSupported
+		* Если что-то пошло не так, ErrSelect
+	*/
+	Search(query string, from, to time.Time, page uint) (Cursor, error)
+
+	/*
+		Issue - проблема, т.е. сводка по всем отчетам с одинаковым отпечатком.
+
+		* fingerprint - отпечаток, как его возвращает Report.Fingerprint
+
+		* Если не найдена, ErrNotFound
+		* Если реализация не поддерживает проблемы, ErrNotSupported
+		* Если что-то пошло не так, ErrSelect
+	*/
+	Issue(fingerprint string) (*Issue, error)
+
+	/*
+		Issues - список проблем в указанном порядке.
+
+		* ord - сначала последние или самые частые
+		* limit - максимальное кол-во проблем в списке, 0 - без ограничения
+
+		* Проблемы учитываются при сохранении отчетов через Model.Import
+		* Кол-во и последние отчеты видны после фиксации транзакции, в которой сохранены отчеты
+		* Порядок списка перестраивается в SweepFdbxIssues, до этого новые отчеты в нем не учтены
+		* Список только читается, поэтому его можно загружать в транзакции только для чтения
+		* Если реализация не поддерживает проблемы, ErrNotSupported
+		* Если что-то пошло не так, ErrSelect
+	*/
+	Issues(ord IssueOrder, limit uint) ([]*Issue, error)
+
+	/*
+		OpenIssues - список открытых проблем сервиса, сначала последние.
+
+		* service - сервис, как в NewProvider, регистр не важен
+		* limit - максимальное кол-во проблем в списке, 0 - без ограничения
+
+		* Открытыми считаются новые, принятые в работу и повторившиеся после исправления
+		* Новые и повторившиеся проблемы попадают в список после прохода SweepFdbxIssues
+		* Если реализация не поддерживает проблемы, ErrNotSupported
+		* Если что-то пошло не так, ErrSelect
+	*/
+	OpenIssues(service string, limit uint) ([]*Issue, error)
+
+	/*
+		SetIssueState - смена состояния разбора проблемы.
+
+		* state - новое состояние, переход должен быть допустим из текущего
+		* user, note - кто меняет и почему, попадают в историю разбора
+
+		* Исправленная проблема при новом отчете сама переходит в IssueRegressed
+		* Если проблема не найдена, ErrNotFound
+		* Если переход недопустим, ErrTransition
+		* Если реализация не поддерживает проблемы, ErrNotSupported
+	*/
+	SetIssueState(fingerprint string, state IssueState, user, note string) (*Issue, error)
+
+	/*
+		AssignIssue - назначение ответственного за проблему, пустой owner снимает назначение.
+
+		* Если проблема не найдена, ErrNotFound
+		* Если реализация не поддерживает проблемы, ErrNotSupported
+	*/
+	AssignIssue(fingerprint, owner, user string) (*Issue, error)
+
+	/*
+		NoteIssue - заметка к проблеме в истории разбора.
+
+		* Если заметка пустая, ErrTriageValidate
+		* Если проблема не найдена, ErrNotFound
+		* Если реализация не поддерживает проблемы, ErrNotSupported
+	*/
+	NoteIssue(fingerprint, user, note string) (*Issue, error)
+}
+
+// Cursor - постраничный перебор отчетов об ошибках
+type Cursor interface {
+	// Идентификатор сохраненного курсора
+	ID() string
+	Empty() bool
+
+	// Подгрузка следующей страницы (но, возможно, с изменением размера), 0 - размер из запроса
+	NextPage(size uint) ([]Model, error)
+
+	// Удаление сохраненного курсора, после этого он недоступен по идентификатору
+	Close() error
 }
 
 // Model - запись ошибки в БД
@@ -27,6 +27,18 @@ func TestProvider(t *testing.T) {
 	suite.Run(t, new(crash.ProviderSuite))
 }
 
+func TestIssue(t *testing.T) {
+	suite.Run(t, new(crash.IssueSuite))
+}
+
+func TestRegistry(t *testing.T) {
+	suite.Run(t, new(crash.RegistrySuite))
+}
+
+func TestFdbxCursor(t *testing.T) {
+	suite.Run(t, new(crash.FdbxCursorSuite))
+}
+
 func TestInterface(t *testing.T) {
 	suite.Run(t, new(InterfaceSuite))
 }
@@ -126,6 +138,21 @@ func (s *InterfaceSuite) TestWorkflow() {
 			s.Equal(report2.AsRFC(), list[1].ExportRFC())
 		}
 
+		// В этой версии нет полнотекстового индекса
+		if _, exp := fac.Search("доступ", from, to, 10); s.Error(exp) {
+			s.True(errx.Is(exp, crash.ErrNotSupported))
+		}
+
+		// И проблем тоже
+		if _, exp := fac.Issues(crash.IssueByRecent, 10); s.Error(exp) {
+			s.True(errx.Is(exp, crash.ErrNotSupported))
+		}
+
+		// И курсоров тоже
+		if _, exp := fac.Select(&crash.Query{From: from, To: to}); s.Error(exp) {
+			s.True(errx.Is(exp, crash.ErrNotSupported))
+		}
+
 		return nil
 	}))
 }
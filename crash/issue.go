@@ -0,0 +1,107 @@
+package crash
+
+import (
+	"encoding/hex"
+	"hash/fnv"
+	"regexp"
+	"time"
+)
+
+// IssueOrder - порядок списка проблем
+type IssueOrder uint8
+
+// Допустимые порядки списка проблем
+const (
+	IssueByRecent IssueOrder = 0 // Сначала проблемы, которые встречались последними
+	IssueByCount  IssueOrder = 1 // Сначала самые частые проблемы
+)
+
+// Параметры отпечатка и проблемы
+var (
+	FingerprintFrames = 3 // Сколько верхних строк стека учитывается в отпечатке
+	IssueSamples      = 5 // Сколько последних идентификаторов отчетов выдается в проблеме
+)
+
+// Issue - проблема, т.е. все отчеты об ошибках с одинаковым отпечатком, и ее разбор
+type Issue struct {
+	Fingerprint string        `json:"fingerprint"`
+	Service     string        `json:"service,omitempty"`
+	Code        string        `json:"code"`
+	Title       string        `json:"title"`
+	Status      uint16        `json:"status"`
+	FirstSeen   time.Time     `json:"first_seen"`
+	LastSeen    time.Time     `json:"last_seen"`
+	Count       uint64        `json:"count"`
+	Samples     []string      `json:"samples"`
+	State       IssueState    `json:"state"`
+	Owner       string        `json:"owner,omitempty"`
+	History     []*IssueEvent `json:"history,omitempty"`
+}
+
+// Что заменяется при нормализации текстов, сначала идентификаторы, потом все остальные числа
+var (
+	normUUID   = regexp.MustCompile(`(?i)[0-9a-f]{8}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{4}-?[0-9a-f]{12}`)
+	normNumber = regexp.MustCompile(`[0-9]+`)
+)
+
+/*
+	Fingerprint - отпечаток отчета, одинаковый для всех отчетов об одной и той же ошибке.
+
+	* Учитываются код, тексты ошибок в цепочке и верхние строки стека самой глубокой ошибки со стеком
+	* Из текстов и стека убираются идентификаторы и числа, в том числе номера строк
+	* Детализация и отладочные данные не учитываются
+*/
+func (r *Report) Fingerprint() string {
+	var stack []string
+
+	h := fnv.New128a()
+	h.Write([]byte(r.Code))
+
+	for i := range r.Entries {
+		h.Write([]byte{0})
+		h.Write([]byte(normalizeText(r.Entries[i].Text)))
+
+		if len(r.Entries[i].Stack) > 0 {
+			stack = r.Entries[i].Stack
+		}
+	}
+
+	if len(stack) > FingerprintFrames {
+		stack = stack[:FingerprintFrames]
+	}
+
+	for i := range stack {
+		h.Write([]byte{1})
+		h.Write([]byte(normalizeText(stack[i])))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// normalizeText - текст, в котором идентификаторы и числа заменены одним символом
+func normalizeText(txt string) string {
+	return normNumber.ReplaceAllString(normUUID.ReplaceAllString(txt, "#"), "#")
+}
+
+// add - учет очередного отчета в проблеме, последние отчеты в начале списка, исправленная открывается снова
+func (i *Issue) add(r *Report) {
+	if i.Count == 0 || r.Created.Before(i.FirstSeen) {
+		i.FirstSeen = r.Created
+	}
+
+	if r.Created.After(i.LastSeen) {
+		i.LastSeen = r.Created
+	}
+
+	i.Count++
+	i.regress(r)
+	i.Code = r.Code
+	i.Service = r.Service
+	i.Title = r.Title
+	i.Status = r.Status
+	i.Samples = append([]string{r.ID}, i.Samples...)
+
+	if len(i.Samples) > IssueSamples {
+		i.Samples = i.Samples[:IssueSamples]
+	}
+}
@@ -0,0 +1,194 @@
+package crash
+
+import (
+	"time"
+
+	"github.com/shestakovda/errx"
+	"github.com/shestakovda/fdbx/v2"
+	"github.com/shestakovda/journal/models"
+	"github.com/shestakovda/typex"
+	"github.com/stretchr/testify/suite"
+)
+
+type IssueSuite struct {
+	suite.Suite
+}
+
+func (s *IssueSuite) TestFingerprint() {
+	rep := func(id, text string, line int) *Report {
+		return &Report{
+			ID:      id,
+			Code:    "testing4034",
+			Created: time.Now(),
+			Entries: []*ReportEntry{
+				{Text: text, Detail: id},
+				{Text: "not found", Stack: []string{"model.go:" + string(rune('0'+line)) + " load", "api.go:12 get"}},
+			},
+		}
+	}
+
+	r1 := rep("a1", "load user 123", 1)
+	r2 := rep("a2", "load user 4567", 2)
+	r3 := rep("a3", "load user 0c6f1f7c-3f5e-4c4c-9d3c-7b1a5a9b8e21", 3)
+
+	// Числа, идентификаторы и номера строк не влияют на отпечаток
+	s.Equal(r1.Fingerprint(), r2.Fingerprint())
+	s.Equal(r1.Fingerprint(), r3.Fingerprint())
+	s.Len(r1.Fingerprint(), 32)
+
+	// А код и текст влияют
+	r3.Entries[0].Text = "save user 1"
+	s.NotEqual(r1.Fingerprint(), r3.Fingerprint())
+
+	r2.Code = "testing5009"
+	s.NotEqual(r1.Fingerprint(), r2.Fingerprint())
+}
+
+func (s *IssueSuite) TestAdd() {
+	iss := new(Issue)
+	now := time.Now()
+
+	for i := 0; i < IssueSamples+2; i++ {
+		iss.add(&Report{
+			ID:      string(rune('a' + i)),
+			Title:   "title",
+			Status:  500,
+			Created: now.Add(time.Duration(i) * time.Minute),
+		})
+	}
+
+	s.Equal(uint64(IssueSamples+2), iss.Count)
+	s.True(now.Equal(iss.FirstSeen))
+	s.True(now.Add(time.Duration(IssueSamples+1) * time.Minute).Equal(iss.LastSeen))
+
+	// Последние отчеты в начале списка
+	if s.Len(iss.Samples, IssueSamples) {
+		s.Equal("g", iss.Samples[0])
+	}
+}
+
+func (s *IssueSuite) TestTriage() {
+	now := time.Now()
+	iss := &Issue{Fingerprint: "fp"}
+	iss.add(&Report{ID: "a", Created: now})
+
+	// Сразу переоткрыть новую нельзя
+	if err := iss.setState(IssueOpen, "user", "", now); s.Error(err) {
+		s.True(errx.Is(err, ErrTransition))
+	}
+
+	s.NoError(iss.setState(IssueAcknowledged, "user", "смотрю", now))
+	s.NoError(iss.setState(IssueResolved, "user", "исправлено", now))
+	s.True(iss.State == IssueResolved)
+	s.False(iss.State.Open())
+
+	// Из исправленной можно только переоткрыть
+	if err := iss.setState(IssueIgnored, "user", "", now); s.Error(err) {
+		s.True(errx.Is(err, ErrTransition))
+	}
+
+	// Новый отчет по исправленной проблеме
+	iss.add(&Report{ID: "b", Created: now.Add(time.Minute)})
+	s.True(iss.State == IssueRegressed)
+	s.True(iss.State.Open())
+
+	iss.assign("owner", "user", now)
+	s.Equal("owner", iss.Owner)
+
+	if err := iss.note("user", "", now); s.Error(err) {
+		s.True(errx.Is(err, ErrTriageValidate))
+	}
+
+	s.NoError(iss.note("user", "заметка", now))
+
+	if s.Len(iss.History, 5) {
+		s.True(iss.History[2].From == IssueResolved)
+		s.True(iss.History[2].To == IssueRegressed)
+		s.Equal("b", iss.History[2].Note)
+		s.Equal("заметка", iss.History[4].Note)
+	}
+
+	s.Equal("regressed", IssueRegressed.String())
+}
+
+func (s *IssueSuite) TestDump() {
+	now := time.Now().UTC()
+	iss := &Issue{
+		Fingerprint: "fp",
+		Service:     "srv",
+		Code:        "code",
+		Title:       "title",
+		Status:      500,
+		Count:       3,
+		Samples:     []string{"a"},
+	}
+
+	iss.assign("owner", "user", now)
+	s.NoError(iss.setState(IssueResolved, "user", "исправлено", now.Add(time.Minute)))
+
+	obj := models.GetRootAsFdbxIssue(fdbx.FlatPack(iss.dump()), 0).UnPack()
+	res := fdbxLoadIssue(obj)
+
+	// Кол-во и последние отчеты хранятся отдельно от описания
+	iss.Count = 0
+	iss.Samples = nil
+	s.Equal(iss, res)
+
+	// Без истории разбора
+	obj = models.GetRootAsFdbxIssue(fdbx.FlatPack((&Issue{Fingerprint: "fp"}).dump()), 0).UnPack()
+	s.Nil(fdbxLoadIssue(obj).History)
+}
+
+func (s *IssueSuite) TestDirty() {
+	now := time.Unix(1000, 0)
+	pref := issueKey(0x4321, nsIssueDirty, nil, "")
+	size := len(pref.Bytes())
+
+	mark := func(fp string, created time.Time) fdbx.Pair {
+		key := issueKey(0x4321, nsIssueDirty, nil, fp).RPart(typex.NewUUID()...)
+		return fdbx.NewPair(key, fdbx.Time2Byte(created))
+	}
+
+	// Отметки группируются по отпечатку, время последнего отчета - самое позднее
+	fps, dirty := groupDirty([]fdbx.Pair{
+		mark("fp2", now),
+		mark("fp1", now.Add(time.Minute)),
+		mark("fp2", now.Add(time.Hour)),
+		mark("fp2", now.Add(time.Second)),
+		fdbx.NewPair(pref.RPart('x'), nil),
+	}, size)
+
+	s.Equal([]string{"fp2", "fp1"}, fps)
+
+	if s.Contains(dirty, "fp2") {
+		s.Equal(uint64(3), dirty["fp2"].count)
+		s.True(now.Add(time.Hour).Equal(dirty["fp2"].last))
+	}
+
+	if s.Contains(dirty, "fp1") {
+		s.Equal(uint64(1), dirty["fp1"].count)
+		s.True(now.Add(time.Minute).Equal(dirty["fp1"].last))
+	}
+}
+
+func (s *IssueSuite) TestOrderKeys() {
+	iss := &Issue{Fingerprint: "fp", Service: "Srv", Count: 2, LastSeen: time.Unix(1000, 0)}
+
+	// Открытая проблема еще и в порядке своего сервиса, регистр сервиса не важен
+	keys := issueOrderKeys(0x4321, iss)
+
+	if s.Len(keys, 3) {
+		s.Equal(issueKey(0x4321, nsIssueRecent, fdbx.Time2Byte(iss.LastSeen), "fp"), keys[0])
+		s.Equal(issueKey(0x4321, nsIssueCount, issueCount(2), "fp"), keys[1])
+		s.Equal(issueKey(0x4321, nsIssueOpen, append([]byte("srv\x00"), fdbx.Time2Byte(iss.LastSeen)...), "fp"), keys[2])
+	}
+
+	// Больше отчетов - дальше в порядке по кол-ву
+	more := *iss
+	more.Count = 256
+	s.True(string(issueOrderKeys(0x4321, &more)[1].Bytes()) > string(keys[1].Bytes()))
+
+	// Исправленная только в общих порядках
+	iss.State = IssueResolved
+	s.Len(issueOrderKeys(0x4321, iss), 2)
+}
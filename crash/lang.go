@@ -0,0 +1,142 @@
+package crash
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLang - язык заголовков Title правил и UnknownErrMsg, они используются, если не подошел ни один перевод
+var DefaultLang = "ru"
+
+// UnknownErrTitles - переводы UnknownErrMsg, который сам считается заголовком на языке DefaultLang
+var UnknownErrTitles = map[string]string{
+	"en": "Request processing error",
+}
+
+/*
+	MatchLang - наиболее подходящий язык из доступных по значению заголовка Accept-Language.
+
+	* Языки перебираются по убыванию веса q, языки с нулевым весом не подходят
+	* Сначала ищется точное совпадение тега, затем совпадение основного языка, например en-US и en
+	* Регистр тегов не учитывается, возвращается тег из списка доступных
+
+	* Если ничего не подошло или указан только *, возвращает пустую строку - значит, язык по-умолчанию
+*/
+func MatchLang(accept string, langs ...string) string {
+	for _, tag := range parseAccept(accept) {
+		if tag == "*" {
+			return ""
+		}
+
+		for i := range langs {
+			if strings.EqualFold(langs[i], tag) {
+				return langs[i]
+			}
+		}
+
+		base := baseLang(tag)
+
+		for i := range langs {
+			if strings.EqualFold(baseLang(langs[i]), base) {
+				return langs[i]
+			}
+		}
+	}
+
+	return ""
+}
+
+// localize - перевод из словаря по Accept-Language, если подходящего нет, то def
+func localize(accept, def string, titles map[string]string) string {
+	if accept == "" || len(titles) == 0 {
+		return def
+	}
+
+	langs := make([]string, 0, len(titles))
+
+	for lang := range titles {
+		langs = append(langs, lang)
+	}
+
+	// Чтобы при равных кандидатах результат не зависел от порядка перебора словаря
+	sort.Strings(langs)
+
+	if lang := MatchLang(accept, langs...); lang != "" {
+		return titles[lang]
+	}
+
+	return def
+}
+
+// translations - копия словаря переводов, в которой заголовок по-умолчанию тоже есть
+func translations(def string, titles map[string]string) map[string]string {
+	if len(titles) == 0 {
+		return nil
+	}
+
+	res := make(map[string]string, len(titles)+1)
+
+	for lang, title := range titles {
+		res[lang] = title
+	}
+
+	if _, ok := res[DefaultLang]; !ok {
+		res[DefaultLang] = def
+	}
+
+	return res
+}
+
+// parseAccept - теги языков Accept-Language в порядке убывания веса
+func parseAccept(accept string) []string {
+	type item struct {
+		tag string
+		q   float64
+	}
+
+	parts := strings.Split(accept, ",")
+	items := make([]item, 0, len(parts))
+
+	for i := range parts {
+		tag, params := parts[i], ""
+
+		if pos := strings.IndexByte(tag, ';'); pos >= 0 {
+			tag, params = tag[:pos], tag[pos+1:]
+		}
+
+		if tag = strings.TrimSpace(tag); tag == "" {
+			continue
+		}
+
+		q := 1.0
+
+		if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+			if val, err := strconv.ParseFloat(params[2:], 64); err == nil {
+				q = val
+			}
+		}
+
+		if q > 0 {
+			items = append(items, item{tag: tag, q: q})
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].q > items[j].q })
+
+	tags := make([]string, len(items))
+
+	for i := range items {
+		tags[i] = items[i].tag
+	}
+
+	return tags
+}
+
+// baseLang - основной язык тега, без региона и письменности
+func baseLang(tag string) string {
+	if pos := strings.IndexAny(tag, "-_"); pos >= 0 {
+		return tag[:pos]
+	}
+	return tag
+}
@@ -0,0 +1,171 @@
+package crash
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProblemContentType - тип содержимого ответа с ошибкой по RFC7807
+const ProblemContentType = "application/problem+json"
+
+// ProblemMaxSize - сколько байт ответа с ошибкой читает ParseProblem, остальное отбрасывается
+var ProblemMaxSize int64 = 1 << 20
+
+/*
+	Problem - ошибка другого сервиса, полученная в формате RFC7807.
+
+	* Сохраняет код, идентификатор и ссылку исходного отчета, чтобы по ним можно было найти его в том сервисе
+	* Если она есть в цепочке ошибки, Provider.Report добавляет эти данные в отладку ее записи
+	* Некорректные параметры при этом попадают и в отчет, как если бы были указаны в отладке с ParamDebugPrefix
+*/
+type Problem struct {
+	ID      string
+	Code    string
+	Link    string
+	Title   string
+	Status  uint16
+	Detail  string
+	Created time.Time
+	Trace   string
+	Params  []*InvalidParam
+	Extra   map[string]interface{}
+}
+
+func (p *Problem) Error() string {
+	return fmt.Sprintf("[ %d ] %s", p.Status, p.Title)
+}
+
+// entry - запись отчета с данными исходного отчета в отладке
+func (p *Problem) entry() *ReportEntry {
+	e := &ReportEntry{
+		Text:   p.Error(),
+		Detail: p.Detail,
+		Debug: map[string]string{
+			"Статус": strconv.Itoa(int(p.Status)),
+		},
+	}
+
+	if p.ID != "" {
+		e.Debug["Идентификатор"] = p.ID
+	}
+
+	if p.Code != "" {
+		e.Debug["Код"] = p.Code
+	}
+
+	if p.Link != "" {
+		e.Debug["Ссылка"] = p.Link
+	}
+
+	if p.Trace != "" {
+		e.Debug["Трассировка"] = p.Trace
+	}
+
+	// Некорректные параметры в том же виде, что и в отладке errx, чтобы они попали в отчет
+	for _, prm := range p.Params {
+		e.Debug[ParamDebugPrefix+prm.Name] = prm.Reason
+	}
+
+	return e
+}
+
+/*
+	WriteProblem - ответ с отчетом об ошибке в формате RFC7807.
+
+	* Устанавливает тип содержимого ProblemContentType и http статус отчета
+	* accept - значение Accept-Language запроса, как в Report.AsRFC
+
+	* Если не удалось отправить ответ, ErrWriteProblem
+*/
+func WriteProblem(w http.ResponseWriter, r *Report, accept ...string) (err error) {
+	var buf []byte
+
+	if buf, err = json.Marshal(r.AsRFC(accept...)); err != nil {
+		return ErrWriteProblem.WithReason(err)
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(buf)))
+	w.WriteHeader(int(r.Status))
+
+	if _, err = w.Write(buf); err != nil {
+		return ErrWriteProblem.WithReason(err)
+	}
+
+	return nil
+}
+
+/*
+	ParseProblem - ошибка другого сервиса из его ответа.
+
+	* Если статус ответа не из групп 4** или 5**, возвращает nil
+	* Иначе возвращает *Problem, в том числе если тело не в формате RFC7807 - тогда только со статусом
+	* Тело ответа читается, но не закрывается
+*/
+func ParseProblem(resp *http.Response) error {
+	if resp == nil || resp.StatusCode < 400 {
+		return nil
+	}
+
+	prb := &Problem{
+		Title:  http.StatusText(resp.StatusCode),
+		Status: uint16(resp.StatusCode),
+	}
+
+	if prb.Title == "" {
+		prb.Title = UnknownErrMsg
+	}
+
+	if resp.Body == nil || !isJSON(resp.Header.Get("Content-Type")) {
+		return prb
+	}
+
+	rfc := new(RFC)
+
+	if buf, err := ioutil.ReadAll(io.LimitReader(resp.Body, ProblemMaxSize)); err != nil || json.Unmarshal(buf, rfc) != nil {
+		return prb
+	}
+
+	prb.ID = rfc.ID
+	prb.Code = rfc.Code
+	prb.Trace = rfc.Trace
+	prb.Extra = rfc.Extra
+	prb.Detail = rfc.Detail
+	prb.Params = rfc.Params
+
+	if rfc.Title != "" {
+		prb.Title = rfc.Title
+	}
+
+	if rfc.Status >= 400 {
+		prb.Status = rfc.Status
+	}
+
+	if rfc.Link != BlankLink {
+		prb.Link = rfc.Link
+	}
+
+	if created, err := time.Parse(time.RFC3339Nano, rfc.Created); err == nil {
+		prb.Created = created
+	}
+
+	return prb
+}
+
+// isJSON - тип содержимого application/problem+json, application/json или другой +json
+func isJSON(ctype string) bool {
+	mtp, _, err := mime.ParseMediaType(ctype)
+
+	if err != nil {
+		return false
+	}
+
+	return mtp == "application/json" || strings.HasSuffix(mtp, "+json")
+}
@@ -1,8 +1,13 @@
 package crash
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -42,52 +47,137 @@ func NewProvider(env envx.Provider, srv string) (_ Provider, err error) {
 type provider struct {
 	sync.RWMutex
 	tpls []*tpl
+	last *tpl
 
 	url string
 	srv string
 }
 
 func (p *provider) Register(status, number int, title string, triggers ...error) {
-	if status < 400 || status >= 600 {
-		panic("Некорректный статус")
+	if len(triggers) == 0 {
+		panic("Пустой список ошибок")
 	}
 
-	if title == "" {
-		panic("Некорректный заголовок")
-	}
+	p.RegisterRule(&Rule{
+		Status:   status,
+		Number:   number,
+		Title:    title,
+		Triggers: triggers,
+	})
+}
 
+func (p *provider) RegisterTitles(status, number int, titles map[string]string, triggers ...error) {
 	if len(triggers) == 0 {
 		panic("Пустой список ошибок")
 	}
 
-	code := fmt.Sprintf("%s%d%d", p.srv, status, number)
+	if titles[DefaultLang] == "" {
+		panic("Нет заголовка на языке по-умолчанию")
+	}
+
+	p.RegisterRule(&Rule{
+		Status:   status,
+		Number:   number,
+		Title:    titles[DefaultLang],
+		Titles:   titles,
+		Triggers: triggers,
+	})
+}
+
+func (p *provider) RegisterRule(rule *Rule) {
+	if rule == nil || (len(rule.Triggers) == 0 && rule.Match == nil && rule.Target == nil) {
+		panic("Пустое правило")
+	}
+
+	t := p.newTpl(rule)
+
+	p.Lock()
+	defer p.Unlock()
+
+	// Вставляем после всех правил с тем же или большим приоритетом
+	i := sort.Search(len(p.tpls), func(i int) bool { return p.tpls[i].prio < t.prio })
+	p.tpls = append(p.tpls, nil)
+	copy(p.tpls[i+1:], p.tpls[i:])
+	p.tpls[i] = t
+}
+
+func (p *provider) SetFallback(rule *Rule) {
+	var t *tpl
+
+	if rule != nil {
+		t = p.newTpl(rule)
+	}
 
 	p.Lock()
 	defer p.Unlock()
-	p.tpls = append(p.tpls, &tpl{
+	p.last = t
+}
+
+// newTpl - проверка правила и шаблон отчета по нему, в случае некорректного правила паникует
+func (p *provider) newTpl(rule *Rule) *tpl {
+	var target reflect.Type
+
+	if rule.Status < 400 || rule.Status >= 600 {
+		panic("Некорректный статус")
+	}
+
+	if rule.Title == "" {
+		panic("Некорректный заголовок")
+	}
+
+	// Те же требования, что и у errors.As, только проверяем сразу при регистрации
+	if rule.Target != nil {
+		if typ := reflect.TypeOf(rule.Target); typ.Kind() != reflect.Ptr || reflect.ValueOf(rule.Target).IsNil() {
+			panic("Тип ошибки должен быть указан непустым указателем")
+		} else if target = typ.Elem(); target.Kind() != reflect.Interface && !target.Implements(errorType) {
+			panic("Тип ошибки должен быть интерфейсом или реализовывать error")
+		}
+	}
+
+	code := fmt.Sprintf("%s%d%d", p.srv, rule.Status, rule.Number)
+	link := p.url + "#" + code
+
+	if rule.Link != "" {
+		link = strings.NewReplacer(
+			"{code}", code,
+			"{status}", strconv.Itoa(rule.Status),
+			"{number}", strconv.Itoa(rule.Number),
+		).Replace(rule.Link)
+	}
+
+	return &tpl{
 		Code:   code,
-		Link:   p.url + "#" + code,
-		Title:  title,
-		Status: uint16(status),
-		Errx:   triggers,
-	})
+		Link:   link,
+		Title:  rule.Title,
+		Status: uint16(rule.Status),
+		Errx:   rule.Triggers,
+
+		prio:   rule.Priority,
+		titles: translations(rule.Title, rule.Titles),
+		number: rule.Number,
+		match:  rule.Match,
+		target: target,
+		detail: rule.Detail,
+	}
 }
 
-func (p *provider) Report(err error) (r *Report) {
+func (p *provider) Report(err error, accept ...string) (r *Report) {
 	if err == nil {
 		return nil
 	}
 
-	t := p.getTpl(err)
+	t, src := p.getTpl(err)
 
 	r = &Report{
 		ID:      typex.NewUUID().Hex(),
 		Code:    t.Code,
 		Link:    t.Link,
-		Title:   t.Title,
+		Title:   localize(strings.Join(accept, ","), t.Title, t.titles),
 		Status:  t.Status,
 		Created: time.Now().UTC(),
 		Entries: make([]*ReportEntry, 0, 8),
+		Service: p.srv,
+		titles:  t.titles,
 	}
 
 	if e, ok := err.(errx.Error); ok {
@@ -108,17 +198,78 @@ func (p *provider) Report(err error) (r *Report) {
 		r.Entries = append(r.Entries, &ReportEntry{Text: err.Error()})
 	}
 
+	// Ошибка другого сервиса в цепочке - сохраняем в ее записи код, идентификатор и ссылку исходного отчета
+	var prb *Problem
+
+	if errors.As(err, &prb) {
+		r.replaceEntry(prb.Error(), prb.entry())
+	}
+
+	// Ошибка проверки - сохраняем в ее записи параметры
+	var vld *ValidationError
+
+	if errors.As(err, &vld) {
+		r.replaceEntry(vld.Error(), vld.entry())
+	}
+
+	if t.detail != nil {
+		if detail := t.detail(src); detail != "" {
+			r.Entries[0].Detail = detail
+		}
+	}
+
+	r.Params = invalidParams(err, r.Entries)
+
 	return r
 }
 
-func (p *provider) getTpl(err error) *tpl {
+func (p *provider) Catalog() *Catalog {
 	p.RLock()
 	defer p.RUnlock()
 
+	cat := &Catalog{
+		Service: p.srv,
+		Codes:   make([]*CatalogCode, 0, len(p.tpls)+1),
+	}
+
 	for i := range p.tpls {
-		if errx.Is(err, p.tpls[i].Errx...) {
-			return p.tpls[i]
+		cat.Codes = append(cat.Codes, p.tpls[i].catalog())
+	}
+
+	last := p.fallback().catalog()
+	last.Fallback = true
+	last.Triggers = append(last.Triggers, "Любая ошибка, для которой нет правила")
+	cat.Codes = append(cat.Codes, last)
+
+	// Для документации удобнее по статусу и номеру, порядок проверки правил есть в приоритете
+	sort.SliceStable(cat.Codes, func(i, j int) bool {
+		if cat.Codes[i].Status != cat.Codes[j].Status {
+			return cat.Codes[i].Status < cat.Codes[j].Status
 		}
+		return cat.Codes[i].Number < cat.Codes[j].Number
+	})
+
+	return cat
+}
+
+// getTpl - первое сработавшее правило и ошибка, по которой оно сработало
+func (p *provider) getTpl(err error) (*tpl, error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	for i := range p.tpls {
+		if src := p.tpls[i].check(err); src != nil {
+			return p.tpls[i], src
+		}
+	}
+
+	return p.fallback(), err
+}
+
+// fallback - правило для ошибок, которым не подошло ни одно зарегистрированное, вызывается под блокировкой
+func (p *provider) fallback() *tpl {
+	if p.last != nil {
+		return p.last
 	}
 
 	code := fmt.Sprintf("%s%d%d", p.srv, http.StatusInternalServerError, 9)
@@ -128,6 +279,8 @@ func (p *provider) getTpl(err error) *tpl {
 		Link:   p.url + "#" + code,
 		Title:  UnknownErrMsg,
 		Status: uint16(http.StatusInternalServerError),
+		titles: translations(UnknownErrMsg, UnknownErrTitles),
+		number: 9,
 	}
 }
 
@@ -137,4 +290,65 @@ type tpl struct {
 	Title  string
 	Status uint16
 	Errx   []error
+
+	prio   int
+	titles map[string]string
+	number int
+	match  func(error) bool
+	target reflect.Type
+	detail func(error) string
+}
+
+// check - ошибка, по которой сработало правило, или nil
+func (t *tpl) check(err error) error {
+	if len(t.Errx) > 0 && errx.Is(err, t.Errx...) {
+		return err
+	}
+
+	if t.match != nil && t.match(err) {
+		return err
+	}
+
+	if t.target != nil {
+		ptr := reflect.New(t.target)
+
+		if errors.As(err, ptr.Interface()) {
+			if src, ok := ptr.Elem().Interface().(error); ok && src != nil {
+				return src
+			}
+			return err
+		}
+	}
+
+	return nil
 }
+
+// catalog - описание правила для каталога, триггеры описываются текстом
+func (t *tpl) catalog() *CatalogCode {
+	code := &CatalogCode{
+		Code:     t.Code,
+		Status:   t.Status,
+		Number:   t.number,
+		Title:    t.Title,
+		Titles:   t.titles,
+		Link:     t.Link,
+		Priority: t.prio,
+		Triggers: make([]string, 0, len(t.Errx)+2),
+	}
+
+	for i := range t.Errx {
+		code.Triggers = append(code.Triggers, t.Errx[i].Error())
+	}
+
+	if t.target != nil {
+		code.Triggers = append(code.Triggers, "Ошибка типа "+t.target.String())
+	}
+
+	if t.match != nil {
+		code.Triggers = append(code.Triggers, "Ошибка по условию")
+	}
+
+	return code
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
@@ -1,7 +1,13 @@
 package crash
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
 
 	"github.com/shestakovda/errx"
 	"github.com/stretchr/testify/assert"
@@ -76,3 +82,331 @@ func (s *ProviderSuite) TestReport() {
 		s.Len(rep.Entries[0].Stack, 7)
 	}
 }
+
+type testLimitError struct{ limit int }
+
+func (e *testLimitError) Error() string { return "limit exceeded" }
+
+func (s *ProviderSuite) TestRules() {
+	prv := NewTestProvider()
+	prv.Register(http.StatusBadRequest, 1, "title1", assert.AnError)
+
+	// Тот же триггер, но с большим приоритетом, поэтому сработает раньше, хоть и зарегистрирован позже
+	prv.RegisterRule(&Rule{
+		Status:   http.StatusConflict,
+		Number:   2,
+		Title:    "title2",
+		Priority: 10,
+		Triggers: []error{assert.AnError},
+	})
+
+	// По типу ошибки, с детализацией из ее полей
+	prv.RegisterRule(&Rule{
+		Status: http.StatusTooManyRequests,
+		Number: 3,
+		Title:  "title3",
+		Target: new(*testLimitError),
+		Detail: func(err error) string { return fmt.Sprintf("Не больше %d", err.(*testLimitError).limit) },
+	})
+
+	// По предикату
+	prv.RegisterRule(&Rule{
+		Status: http.StatusGatewayTimeout,
+		Number: 4,
+		Title:  "title4",
+		Match:  func(err error) bool { return strings.HasPrefix(err.Error(), "timeout") },
+	})
+
+	if rep := prv.Report(errx.New("wrap").WithReason(assert.AnError)); s.NotNil(rep) {
+		s.Equal("testing4092", rep.Code)
+	}
+
+	if rep := prv.Report(errx.New("wrap").WithReason(&testLimitError{limit: 5})); s.NotNil(rep) {
+		s.Equal("testing4293", rep.Code)
+		s.Equal("title3", rep.Title)
+		s.Equal("Не больше 5", rep.Entries[0].Detail)
+	}
+
+	if rep := prv.Report(errors.New("timeout reading")); s.NotNil(rep) {
+		s.Equal("testing5044", rep.Code)
+		s.Empty(rep.Entries[0].Detail)
+	}
+
+	// Некорректные правила
+	s.Panics(func() { prv.RegisterRule(&Rule{Status: 400, Title: "title"}) })
+	s.Panics(func() { prv.RegisterRule(&Rule{Status: 400, Title: "title", Target: testLimitError{}}) })
+	s.Panics(func() { prv.RegisterRule(&Rule{Status: 400, Title: "title", Target: new(int)}) })
+	s.Panics(func() { prv.RegisterRule(&Rule{Status: 200, Title: "title", Match: func(error) bool { return true }}) })
+}
+
+func (s *ProviderSuite) TestCatalog() {
+	prv := NewTestProvider()
+	prv.Register(http.StatusNotFound, 2, "title2", assert.AnError)
+	prv.RegisterRule(&Rule{
+		Status:   http.StatusBadRequest,
+		Number:   1,
+		Title:    "title | 1",
+		Link:     "/docs/{code}",
+		Priority: 5,
+		Target:   new(*testLimitError),
+		Match:    func(error) bool { return false },
+	})
+
+	cat := prv.Catalog()
+	s.Equal("testing", cat.Service)
+
+	if s.Len(cat.Codes, 3) {
+		s.Equal("testing4001", cat.Codes[0].Code)
+		s.Equal("/docs/testing4001", cat.Codes[0].Link)
+		s.Equal(5, cat.Codes[0].Priority)
+		s.Equal([]string{"Ошибка типа *crash.testLimitError", "Ошибка по условию"}, cat.Codes[0].Triggers)
+
+		s.Equal("testing4042", cat.Codes[1].Code)
+		s.Equal([]string{assert.AnError.Error()}, cat.Codes[1].Triggers)
+		s.False(cat.Codes[1].Fallback)
+
+		s.Equal("testing5009", cat.Codes[2].Code)
+		s.Equal(UnknownErrMsg, cat.Codes[2].Title)
+		s.True(cat.Codes[2].Fallback)
+	}
+
+	var buf strings.Builder
+	s.NoError(cat.WriteMarkdown(&buf))
+	s.Contains(buf.String(), "<a id=\"testing4042\"></a>")
+	s.Contains(buf.String(), "| [testing4001](#testing4001) | 400 | title \\| 1 |")
+
+	// Обработчик по-умолчанию отдает Markdown, по запросу - JSON
+	hdl := CatalogHandler(prv)
+
+	rec := httptest.NewRecorder()
+	hdl.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/errors", nil))
+	s.Equal(http.StatusOK, rec.Code)
+	s.Equal(buf.String(), rec.Body.String())
+	s.Equal(strconv.Itoa(buf.Len()), rec.Header().Get("Content-Length"))
+
+	rec = httptest.NewRecorder()
+	hdl.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/errors?format=json", nil))
+	s.Equal("application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	res := new(Catalog)
+	s.NoError(json.Unmarshal(rec.Body.Bytes(), res))
+	s.Equal(cat, res)
+
+	rec = httptest.NewRecorder()
+	hdl.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/errors", nil))
+	s.Equal(http.StatusMethodNotAllowed, rec.Code)
+}
+
+func (s *ProviderSuite) TestLocalize() {
+	s.Equal("en", MatchLang("en-US,en;q=0.9,ru;q=0.8", "en", "ru"))
+	s.Equal("ru", MatchLang("en;q=0.5, ru-RU", "en", "ru"))
+	s.Equal("pt-BR", MatchLang("PT-br", "pt", "pt-BR"))
+	s.Equal("pt", MatchLang("pt-PT", "pt", "en"))
+	s.Equal("", MatchLang("de, *;q=0.1", "en", "ru"))
+	s.Equal("", MatchLang("en;q=0", "en", "ru"))
+	s.Equal("", MatchLang("", "en", "ru"))
+
+	prv := NewTestProvider()
+	prv.RegisterTitles(http.StatusForbidden, 1, map[string]string{
+		"ru": "Доступ запрещен",
+		"en": "Access denied",
+	}, assert.AnError)
+
+	s.Panics(func() {
+		prv.RegisterTitles(http.StatusForbidden, 2, map[string]string{"en": "Forbidden"}, assert.AnError)
+	})
+
+	// Без языка и для неизвестного языка - заголовок по-умолчанию
+	s.Equal("Доступ запрещен", prv.Report(assert.AnError).Title)
+	s.Equal("Доступ запрещен", prv.Report(assert.AnError, "de").Title)
+
+	rep := prv.Report(assert.AnError, "en-GB,en;q=0.8")
+	s.Equal("Access denied", rep.Title)
+	s.Equal("Access denied", rep.AsRFC().Title)
+	s.Equal("Доступ запрещен", rep.AsRFC("ru").Title)
+	s.Equal("Доступ запрещен", rep.Brief().AsRFC("ru").Title)
+
+	// Незарегистрированная ошибка тоже переводится
+	s.Equal(UnknownErrMsg, prv.Report(errors.New("unknown")).Title)
+	s.Equal(UnknownErrTitles["en"], prv.Report(errors.New("unknown")).AsRFC("en").Title)
+
+	// Каталог отдает переводы и выбирает заголовок по Accept-Language
+	cat := prv.Catalog()
+	s.Equal("Access denied", cat.Codes[0].Titles["en"])
+	s.Equal("Access denied", cat.Localize("en").Codes[0].Title)
+	s.Equal("Доступ запрещен", cat.Codes[0].Title)
+
+	req := httptest.NewRequest(http.MethodGet, "/errors", nil)
+	req.Header.Set("Accept-Language", "en")
+	rec := httptest.NewRecorder()
+	CatalogHandler(prv).ServeHTTP(rec, req)
+	s.Contains(rec.Body.String(), "Access denied")
+}
+
+func (s *ProviderSuite) TestFallback() {
+	prv := NewTestProvider()
+	prv.SetFallback(&Rule{
+		Status: http.StatusServiceUnavailable,
+		Number: 1,
+		Title:  "Попробуйте позже",
+		Titles: map[string]string{"en": "Try again later"},
+		Detail: func(err error) string { return "Причина: " + err.Error() },
+	})
+
+	if rep := prv.Report(assert.AnError, "en"); s.NotNil(rep) {
+		s.Equal("testing5031", rep.Code)
+		s.Equal("Try again later", rep.Title)
+		s.Equal(http.StatusServiceUnavailable, int(rep.Status))
+		s.Equal("Причина: "+assert.AnError.Error(), rep.Entries[0].Detail)
+	}
+
+	if cat := prv.Catalog(); s.Len(cat.Codes, 1) {
+		s.Equal("testing5031", cat.Codes[0].Code)
+		s.True(cat.Codes[0].Fallback)
+	}
+
+	s.Panics(func() { prv.SetFallback(&Rule{Status: http.StatusOK, Title: "ok"}) })
+
+	// Сброс на правило по-умолчанию
+	prv.SetFallback(nil)
+	s.Equal("testing5009", prv.Report(assert.AnError).Code)
+}
+
+func (s *ProviderSuite) TestErrxRules() {
+	prv := NewTestProvider()
+	prv.Register(http.StatusConflict, 1, "title1", assert.AnError)
+	RegisterErrxRules(prv)
+
+	if rep := prv.Report(errx.New("not found").WithReason(errx.ErrNotFound), "en"); s.NotNil(rep) {
+		s.Equal("testing4040", rep.Code)
+		s.Equal(http.StatusNotFound, int(rep.Status))
+		s.Equal("Not found", rep.Title)
+	}
+
+	s.Equal(http.StatusBadRequest, int(prv.Report(ErrQueryValidate).Status))
+	s.Equal(http.StatusNotImplemented, int(prv.Report(ErrNotSupported).Status))
+
+	// Правила приложения срабатывают раньше, даже если зарегистрированы до стандартных
+	s.Equal("testing4091", prv.Report(errx.ErrForbidden.WithReason(assert.AnError)).Code)
+
+	// Все остальное как раньше
+	s.Equal("testing5009", prv.Report(errors.New("unknown")).Code)
+}
+
+func (s *ProviderSuite) TestProblem() {
+	prv := NewTestProvider()
+	prv.RegisterTitles(http.StatusNotFound, 2, map[string]string{"ru": "Не найдено", "en": "Not found"}, assert.AnError)
+
+	rep := prv.Report(errx.New("wrap").WithReason(assert.AnError).WithDetail("Нет такого"))
+	rec := httptest.NewRecorder()
+	s.NoError(WriteProblem(rec, rep, "en"))
+	s.Equal(http.StatusNotFound, rec.Code)
+	s.Equal(ProblemContentType, rec.Header().Get("Content-Type"))
+
+	// Клиент получает ошибку с данными исходного отчета
+	err := ParseProblem(rec.Result())
+	prb := new(Problem)
+
+	if s.True(errors.As(err, &prb)) {
+		s.Equal(rep.ID, prb.ID)
+		s.Equal("testing4042", prb.Code)
+		s.Equal("#testing4042", prb.Link)
+		s.Equal("Not found", prb.Title)
+		s.Equal(uint16(http.StatusNotFound), prb.Status)
+		s.Equal("Нет такого", prb.Detail)
+		s.True(rep.Created.Equal(prb.Created))
+	}
+
+	// Ее можно включить в цепочку своей ошибки
+	down := NewTestProvider()
+	down.Register(http.StatusBadGateway, 1, "Ошибка внешнего сервиса", errx.ErrUnavailable)
+
+	if rep2 := down.Report(errx.ErrUnavailable.WithReason(err)); s.NotNil(rep2) && s.Len(rep2.Entries, 2) {
+		s.Equal("testing5021", rep2.Code)
+		s.Equal(prb.Error(), rep2.Entries[1].Text)
+		s.Equal("Нет такого", rep2.Entries[1].Detail)
+		s.Equal(rep.ID, rep2.Entries[1].Debug["Идентификатор"])
+		s.Equal("testing4042", rep2.Entries[1].Debug["Код"])
+		s.Equal("404", rep2.Entries[1].Debug["Статус"])
+	}
+
+	// Успешный ответ - не ошибка
+	s.NoError(ParseProblem(&http.Response{StatusCode: http.StatusOK}))
+
+	// Ответ не в формате RFC7807 - только статус
+	rec = httptest.NewRecorder()
+	http.Error(rec, "oops", http.StatusServiceUnavailable)
+
+	if s.True(errors.As(ParseProblem(rec.Result()), &prb)) {
+		s.Equal(uint16(http.StatusServiceUnavailable), prb.Status)
+		s.Equal(http.StatusText(http.StatusServiceUnavailable), prb.Title)
+		s.Empty(prb.ID)
+	}
+}
+
+func (s *ProviderSuite) TestExtensions() {
+	prv := NewTestProvider()
+	RegisterErrxRules(prv)
+
+	vld := NewValidationError().Add("email", "Неверный формат").Add("age", "Меньше нуля")
+	err := errx.ErrBadRequest.WithReason(vld).WithDebug(errx.Debug{"param:name": "Пустое имя", "other": 1})
+
+	rep := prv.Report(err)
+	rep.Trace = "trace"
+	rep.Extra = map[string]interface{}{"balance": 30, "title": "ignored"}
+
+	s.Equal([]*InvalidParam{
+		{Name: "email", Reason: "Неверный формат"},
+		{Name: "age", Reason: "Меньше нуля"},
+		{Name: "name", Reason: "Пустое имя"},
+	}, rep.Params)
+
+	// Параметры сохраняются в отладке записей, поэтому восстанавливаются по ним
+	if s.Len(rep.Entries, 2) {
+		s.Equal("Неверный формат", rep.Entries[1].Debug["param:email"])
+		s.ElementsMatch(rep.Params, invalidParams(nil, rep.Entries))
+	}
+
+	// Члены расширения на верхнем уровне
+	buf, exp := json.Marshal(rep.AsRFC())
+	s.Require().NoError(exp)
+
+	obj := make(map[string]interface{})
+	s.Require().NoError(json.Unmarshal(buf, &obj))
+	s.Equal("trace", obj["trace"])
+	s.Equal(float64(30), obj["balance"])
+	s.Equal(rep.Title, obj["title"])
+	s.Len(obj["invalid-params"], 3)
+
+	rfc := new(RFC)
+	s.Require().NoError(json.Unmarshal(buf, rfc))
+	s.Equal(map[string]interface{}{"balance": float64(30)}, rfc.Extra)
+	s.Equal(rep.Params, rfc.Params)
+	s.Equal("trace", rfc.Trace)
+
+	// Без расширений обычный вид
+	buf, exp = json.Marshal(&RFC{ID: "id", Status: 500})
+	s.Require().NoError(exp)
+	s.Equal(`{"id":"id","code":"","type":"","title":"","status":500,"created":""}`, string(buf))
+
+	// Клиент получает все члены расширения
+	rec := httptest.NewRecorder()
+	s.Require().NoError(WriteProblem(rec, rep))
+
+	prb := new(Problem)
+
+	if s.True(errors.As(ParseProblem(rec.Result()), &prb)) {
+		s.Equal("trace", prb.Trace)
+		s.Equal(rep.Params, prb.Params)
+		s.Equal(map[string]interface{}{"balance": float64(30)}, prb.Extra)
+	}
+
+	// И передает некорректные параметры дальше в своем отчете
+	if rep2 := prv.Report(errx.ErrBadRequest.WithReason(prb)); s.NotNil(rep2) {
+		s.ElementsMatch(rep.Params, rep2.Params)
+		s.Equal("trace", rep2.Entries[1].Debug["Трассировка"])
+	}
+
+	// Для ошибок сервиса параметры не отдаются
+	s.Empty(prv.Report(errx.ErrInternal.WithReason(vld)).AsRFC().Params)
+}
@@ -0,0 +1,201 @@
+package crash
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Язык заголовков по-умолчанию, если в реестре он не указан
+const RegistryLang = "ru"
+
+/*
+	Registry - реестр кодов ошибок, описанный в файле, а не в коде.
+
+	* Lang - язык заголовка Title правил, остальные переводы выбираются в отчетах по Accept-Language
+	* Link - шаблон ссылки на документацию для всех кодов, как в Rule.Link
+	* Codes - описания кодов
+
+	* Триггеры в файле указываются именами, сами ошибки привязываются в приложении через Bind
+*/
+type Registry struct {
+	Lang  string          `json:"lang,omitempty"`
+	Link  string          `json:"link,omitempty"`
+	Codes []*RegistryCode `json:"codes"`
+
+	errs map[string]error
+}
+
+/*
+	RegistryCode - описание одного кода ошибки в реестре.
+
+	* Status, Number, Priority - как в Rule
+	* Title - заголовки по языкам, на языке реестра обязателен
+	* Link - шаблон ссылки на документацию, если пустой, то из реестра
+	* Triggers - имена внутренних ошибок, каждое имя может быть только у одного кода
+*/
+type RegistryCode struct {
+	Status   int               `json:"status"`
+	Number   int               `json:"number"`
+	Title    map[string]string `json:"title"`
+	Link     string            `json:"link,omitempty"`
+	Priority int               `json:"priority,omitempty"`
+	Triggers []string          `json:"triggers"`
+}
+
+/*
+	LoadRegistry - загрузка и проверка реестра кодов ошибок в формате JSON.
+
+	* Статус должен быть группы 4** или 5**, номер неотрицательным
+	* Заголовок на языке реестра и хотя бы один триггер обязательны
+
+	* Неизвестные поля, например с опечаткой в названии, считаются ошибкой, а не пропускаются
+
+	* Если файл некорректный, ErrRegistryValidate, в деталях перечислены все найденные проблемы,
+	* в том числе пустые описания, коды с одинаковым статусом и номером и триггеры, указанные несколько раз
+*/
+func LoadRegistry(r io.Reader) (_ *Registry, err error) {
+	reg := new(Registry)
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	// Текст ошибки разбора в деталях, чтобы было видно, какое поле не подошло
+	if err = dec.Decode(reg); err != nil {
+		return nil, ErrRegistryValidate.WithReason(err).WithDetail("%s", err)
+	}
+
+	if reg.Lang == "" {
+		reg.Lang = RegistryLang
+	}
+
+	if err = reg.validate(); err != nil {
+		return nil, err
+	}
+
+	return reg, nil
+}
+
+/*
+	Bind - привязка внутренней ошибки к имени триггера в реестре.
+
+	* Обычно вызывается для всех ошибок-образцов пакета, например Bind("ErrForbidden", ErrForbidden)
+	* Привязывать можно и имена, которых нет в реестре, они просто не используются
+*/
+func (r *Registry) Bind(name string, err error) *Registry {
+	if r.errs == nil {
+		r.errs = make(map[string]error)
+	}
+
+	r.errs[name] = err
+	return r
+}
+
+/*
+	Apply - регистрация всех кодов реестра в провайдере.
+
+	* Если у какого-то триггера нет привязанной ошибки, ErrRegistryValidate со списком таких имен,
+	* в этом случае в провайдере ничего не регистрируется
+	* Пустые описания кодов пропускаются, в LoadRegistry они считаются ошибкой
+*/
+func (r *Registry) Apply(prv Provider) error {
+	var miss []string
+
+	rules := make([]*Rule, 0, len(r.Codes))
+
+	for _, code := range r.Codes {
+		if code == nil {
+			continue
+		}
+
+		rule := &Rule{
+			Status:   code.Status,
+			Number:   code.Number,
+			Title:    code.Title[r.Lang],
+			Titles:   code.Title,
+			Link:     code.Link,
+			Priority: code.Priority,
+			Triggers: make([]error, 0, len(code.Triggers)),
+		}
+
+		if rule.Link == "" {
+			rule.Link = r.Link
+		}
+
+		for _, name := range code.Triggers {
+			if err, ok := r.errs[name]; ok {
+				rule.Triggers = append(rule.Triggers, err)
+			} else {
+				miss = append(miss, name)
+			}
+		}
+
+		rules = append(rules, rule)
+	}
+
+	if len(miss) > 0 {
+		return ErrRegistryValidate.WithDetail("Не привязаны ошибки: %s", strings.Join(miss, ", "))
+	}
+
+	for i := range rules {
+		prv.RegisterRule(rules[i])
+	}
+
+	return nil
+}
+
+// validate - проверка всех кодов сразу, чтобы перечислить все проблемы одной ошибкой
+func (r *Registry) validate() error {
+	var fails []string
+
+	codes := make(map[string]int, len(r.Codes))
+	names := make(map[string]int, len(r.Codes))
+
+	for i, code := range r.Codes {
+		if code == nil {
+			fails = append(fails, fmt.Sprintf("описание #%d: пустое", i+1))
+			continue
+		}
+
+		key := fmt.Sprintf("%d%d", code.Status, code.Number)
+
+		if code.Status < 400 || code.Status >= 600 {
+			fails = append(fails, fmt.Sprintf("код %s: некорректный статус", key))
+		}
+
+		if code.Number < 0 {
+			fails = append(fails, fmt.Sprintf("код %s: некорректный номер", key))
+		}
+
+		if code.Title[r.Lang] == "" {
+			fails = append(fails, fmt.Sprintf("код %s: нет заголовка на языке %s", key, r.Lang))
+		}
+
+		if len(code.Triggers) == 0 {
+			fails = append(fails, fmt.Sprintf("код %s: пустой список триггеров", key))
+		}
+
+		if j, ok := codes[key]; ok {
+			fails = append(fails, fmt.Sprintf("код %s: повторяет описание #%d", key, j+1))
+		} else {
+			codes[key] = i
+		}
+
+		for _, name := range code.Triggers {
+			if j, ok := names[name]; ok {
+				fails = append(fails, fmt.Sprintf("код %s: триггер %s уже указан у кода %d%d",
+					key, name, r.Codes[j].Status, r.Codes[j].Number))
+			} else {
+				names[name] = i
+			}
+		}
+	}
+
+	if len(fails) == 0 {
+		return nil
+	}
+
+	sort.Strings(fails)
+	return ErrRegistryValidate.WithDetail("%s", strings.Join(fails, "; "))
+}
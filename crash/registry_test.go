@@ -0,0 +1,112 @@
+package crash
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/shestakovda/errx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RegistrySuite struct {
+	suite.Suite
+}
+
+func (s *RegistrySuite) TestLoad() {
+	reg, err := LoadRegistry(strings.NewReader(`{
+		"lang": "en",
+		"link": "https://docs.example.com/errors/{code}",
+		"codes": [
+			{"status": 403, "number": 1, "title": {"ru": "Доступ запрещен", "en": "Forbidden"}, "triggers": ["ErrForbidden"]},
+			{"status": 404, "number": 2, "title": {"en": "Not found"}, "link": "/help/{status}/{number}", "triggers": ["AnError"]}
+		]
+	}`))
+	s.Require().NoError(err)
+	s.Equal("en", reg.Lang)
+	s.Len(reg.Codes, 2)
+
+	// Пока ошибки не привязаны, регистрировать нечего
+	prv := NewTestProvider()
+	err = reg.Apply(prv)
+	s.True(errx.Is(err, ErrRegistryValidate))
+	s.True(errx.Is(err, errx.ErrBadRequest))
+	s.Contains(registryDetail(err), "ErrForbidden")
+	s.Contains(registryDetail(err), "AnError")
+	s.Equal(http.StatusInternalServerError, int(prv.Report(assert.AnError).Status))
+
+	s.NoError(reg.Bind("ErrForbidden", errx.ErrForbidden).Bind("AnError", assert.AnError).Apply(prv))
+
+	if rep := prv.Report(errx.ErrForbidden); s.NotNil(rep) {
+		s.Equal(http.StatusForbidden, int(rep.Status))
+		s.Equal("Forbidden", rep.Title)
+		s.Equal("https://docs.example.com/errors/testing4031", rep.Link)
+		s.Equal("Доступ запрещен", rep.AsRFC("ru").Title)
+	}
+
+	if rep := prv.Report(assert.AnError); s.NotNil(rep) {
+		s.Equal(http.StatusNotFound, int(rep.Status))
+		s.Equal("Not found", rep.Title)
+		s.Equal("/help/404/2", rep.Link)
+	}
+}
+
+func (s *RegistrySuite) TestValidate() {
+	// Язык по-умолчанию, заголовка на нем нет
+	_, err := LoadRegistry(strings.NewReader(`{"codes": [{"status": 400, "number": 1, "title": {"en": "Bad"}, "triggers": ["A"]}]}`))
+	s.True(errx.Is(err, ErrRegistryValidate))
+	s.Contains(registryDetail(err), "нет заголовка на языке ru")
+
+	// Все проблемы перечисляются сразу
+	_, err = LoadRegistry(strings.NewReader(`{"codes": [
+		{"status": 400, "number": 1, "title": {"ru": "Один"}, "triggers": ["A", "B"]},
+		{"status": 400, "number": 1, "title": {"ru": "Два"}, "triggers": ["C"]},
+		{"status": 409, "number": 3, "title": {"ru": "Три"}, "triggers": ["B"]},
+		{"status": 200, "number": 4, "title": {"ru": "Четыре"}, "triggers": []}
+	]}`))
+	s.True(errx.Is(err, ErrRegistryValidate))
+	s.Contains(registryDetail(err), "код 4001: повторяет описание #1")
+	s.Contains(registryDetail(err), "код 4093: триггер B уже указан у кода 4001")
+	s.Contains(registryDetail(err), "код 2004: некорректный статус")
+	s.Contains(registryDetail(err), "код 2004: пустой список триггеров")
+
+	// Некорректный JSON
+	_, err = LoadRegistry(strings.NewReader(`{"codes": [`))
+	s.True(errx.Is(err, ErrRegistryValidate))
+
+	// Пустое описание кода
+	_, err = LoadRegistry(strings.NewReader(`{"codes": [null]}`))
+	s.True(errx.Is(err, ErrRegistryValidate))
+	s.Contains(registryDetail(err), "описание #1: пустое")
+
+	// Опечатка в названии поля
+	_, err = LoadRegistry(strings.NewReader(`{"codes": [{"status": 400, "number": 1, "tittle": {"ru": "Один"}, "triggers": ["A"]}]}`))
+	s.True(errx.Is(err, ErrRegistryValidate))
+	s.Contains(registryDetail(err), "tittle")
+}
+
+func (s *RegistrySuite) TestManual() {
+	reg := &Registry{
+		Lang:  RegistryLang,
+		Codes: []*RegistryCode{nil, {Status: 409, Number: 5, Title: map[string]string{"ru": "Конфликт"}, Triggers: []string{"A"}}},
+	}
+
+	prv := NewTestProvider()
+	s.NoError(reg.Bind("A", assert.AnError).Apply(prv))
+
+	if rep := prv.Report(assert.AnError); s.NotNil(rep) {
+		s.Equal(http.StatusConflict, int(rep.Status))
+		s.Equal("Конфликт", rep.Title)
+	}
+}
+
+// registryDetail - детализация ошибки реестра, в ней перечислены проблемы
+func registryDetail(err error) string {
+	var exp errx.Error
+
+	if errx.As(err, &exp) {
+		return exp.Export().Detail
+	}
+
+	return ""
+}
@@ -0,0 +1,109 @@
+package crash
+
+import (
+	"time"
+
+	"github.com/shestakovda/errx"
+)
+
+// IssueState - состояние разбора проблемы
+type IssueState uint8
+
+// Допустимые состояния разбора
+const (
+	IssueOpen         IssueState = 0 // Новая проблема, еще не разобрана
+	IssueAcknowledged IssueState = 1 // Проблема принята в работу
+	IssueResolved     IssueState = 2 // Проблема исправлена
+	IssueIgnored      IssueState = 3 // Проблему решили не исправлять
+	IssueRegressed    IssueState = 4 // Исправленная проблема повторилась
+)
+
+// Сколько последних событий разбора хранится в проблеме
+var IssueHistory = 100
+
+// Допустимые переходы между состояниями, остальные запрещены
+var issueTransitions = map[IssueState][]IssueState{
+	IssueOpen:         {IssueAcknowledged, IssueResolved, IssueIgnored},
+	IssueAcknowledged: {IssueOpen, IssueResolved, IssueIgnored},
+	IssueResolved:     {IssueOpen},
+	IssueIgnored:      {IssueOpen},
+	IssueRegressed:    {IssueAcknowledged, IssueResolved, IssueIgnored},
+}
+
+// IssueEvent - событие разбора проблемы: смена состояния, назначение или заметка
+type IssueEvent struct {
+	Time  time.Time  `json:"time"`
+	User  string     `json:"user,omitempty"`
+	From  IssueState `json:"from"`
+	To    IssueState `json:"to"`
+	Owner string     `json:"owner,omitempty"`
+	Note  string     `json:"note,omitempty"`
+}
+
+func (s IssueState) String() string {
+	switch s {
+	case IssueOpen:
+		return "open"
+	case IssueAcknowledged:
+		return "acknowledged"
+	case IssueResolved:
+		return "resolved"
+	case IssueIgnored:
+		return "ignored"
+	case IssueRegressed:
+		return "regressed"
+	}
+	return "unknown"
+}
+
+// Open - проблема требует внимания, т.е. не исправлена и не отложена
+func (s IssueState) Open() bool {
+	return s == IssueOpen || s == IssueAcknowledged || s == IssueRegressed
+}
+
+// setState - смена состояния, если такой переход допустим
+func (i *Issue) setState(to IssueState, user, note string, now time.Time) error {
+	for _, next := range issueTransitions[i.State] {
+		if next == to {
+			i.event(&IssueEvent{Time: now, User: user, From: i.State, To: to, Note: note})
+			i.State = to
+			return nil
+		}
+	}
+
+	return ErrTransition.WithDebug(errx.Debug{
+		"Отпечаток": i.Fingerprint,
+		"Состояние": i.State.String(),
+		"Переход":   to.String(),
+	})
+}
+
+// assign - назначение ответственного, пустой снимает назначение
+func (i *Issue) assign(owner, user string, now time.Time) {
+	i.Owner = owner
+	i.event(&IssueEvent{Time: now, User: user, From: i.State, To: i.State, Owner: owner})
+}
+
+// note - заметка к проблеме без смены состояния
+func (i *Issue) note(user, note string, now time.Time) error {
+	if note == "" {
+		return ErrTriageValidate.WithDetail("Пустая заметка")
+	}
+
+	i.event(&IssueEvent{Time: now, User: user, From: i.State, To: i.State, Note: note})
+	return nil
+}
+
+// regress - повторение исправленной проблемы открывает ее снова
+func (i *Issue) regress(r *Report) {
+	if i.State == IssueResolved {
+		i.event(&IssueEvent{Time: r.Created, From: i.State, To: IssueRegressed, Note: r.ID})
+		i.State = IssueRegressed
+	}
+}
+
+func (i *Issue) event(e *IssueEvent) {
+	if i.History = append(i.History, e); len(i.History) > IssueHistory {
+		i.History = i.History[len(i.History)-IssueHistory:]
+	}
+}
@@ -0,0 +1,115 @@
+package crash
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+	ParamDebugPrefix - начало ключа отладки errx, по которому в отчет попадает некорректный параметр.
+
+	* Например, errx.ErrBadRequest.WithDebug(errx.Debug{"param:email": "Неверный формат"})
+*/
+var ParamDebugPrefix = "param:"
+
+/*
+	ValidationError - ошибка проверки параметров запроса.
+
+	* Может быть где угодно в цепочке, ее параметры попадают в invalid-params отчета
+	* Сама по себе ни на какой статус не влияет, для этого нужна причина вроде errx.ErrBadRequest
+*/
+type ValidationError struct {
+	Params []*InvalidParam
+}
+
+// NewValidationError - конструктор ошибки проверки параметров
+func NewValidationError() *ValidationError {
+	return &ValidationError{Params: make([]*InvalidParam, 0, 4)}
+}
+
+// Add - добавление некорректного параметра и причины
+func (e *ValidationError) Add(name, reason string) *ValidationError {
+	e.Params = append(e.Params, &InvalidParam{Name: name, Reason: reason})
+	return e
+}
+
+// Empty - нет ни одного некорректного параметра
+func (e *ValidationError) Empty() bool {
+	return len(e.Params) == 0
+}
+
+func (e *ValidationError) Error() string {
+	names := make([]string, len(e.Params))
+
+	for i := range e.Params {
+		names[i] = e.Params[i].Name
+	}
+
+	return "Некорректные параметры: " + strings.Join(names, ", ")
+}
+
+// entry - запись отчета с параметрами в отладке, чтобы они сохранялись вместе с отчетом
+func (e *ValidationError) entry() *ReportEntry {
+	ent := &ReportEntry{
+		Text:  e.Error(),
+		Debug: make(map[string]string, len(e.Params)),
+	}
+
+	for _, prm := range e.Params {
+		ent.Debug[ParamDebugPrefix+prm.Name] = prm.Reason
+	}
+
+	return ent
+}
+
+// invalidParams - параметры из ошибки проверки и из отладки записей отчета, без повторов
+func invalidParams(err error, entries []*ReportEntry) []*InvalidParam {
+	var vld *ValidationError
+	var res []*InvalidParam
+
+	uniq := make(map[InvalidParam]bool)
+
+	add := func(prm *InvalidParam) {
+		if !uniq[*prm] {
+			uniq[*prm] = true
+			res = append(res, prm)
+		}
+	}
+
+	if errors.As(err, &vld) {
+		for i := range vld.Params {
+			add(vld.Params[i])
+		}
+	}
+
+	for i := range entries {
+		keys := make([]string, 0, len(entries[i].Debug))
+
+		for key := range entries[i].Debug {
+			if strings.HasPrefix(key, ParamDebugPrefix) {
+				keys = append(keys, key)
+			}
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			add(&InvalidParam{
+				Name:   strings.TrimPrefix(key, ParamDebugPrefix),
+				Reason: debugText(entries[i].Debug[key]),
+			})
+		}
+	}
+
+	return res
+}
+
+// debugText - значение отладки errx без кавычек, которые добавляются к строкам при форматировании
+func debugText(val string) string {
+	if txt, err := strconv.Unquote(val); err == nil {
+		return txt
+	}
+	return val
+}
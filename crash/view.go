@@ -1,6 +1,7 @@
 package crash
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -15,6 +16,15 @@ type Report struct {
 	Status  uint16
 	Created time.Time
 	Entries []*ReportEntry
+	Service string
+
+	// Члены расширения RFC7807, параметры хранятся в отладке записей, Extra не сохраняется
+	Trace  string
+	Params []*InvalidParam
+	Extra  map[string]interface{}
+
+	// Переводы заголовка, есть только у отчетов, сформированных провайдером, и не сохраняются
+	titles map[string]string
 }
 
 // ReportEntry - основное представление ошибки в цепочке
@@ -25,15 +35,101 @@ type ReportEntry struct {
 	Debug  map[string]string
 }
 
+/*
+	RFC - представление отчета в формате RFC7807.
+
+	* Trace - идентификатор трассировки, например записи журнала, в которой возник отчет
+	* Params - некорректные параметры запроса, член invalid-params
+	* Extra - произвольные члены расширения, сериализуются на верхнем уровне наравне с остальными
+
+	* Ключи Extra, совпадающие с членами этой структуры, игнорируются
+*/
 type RFC struct {
-	ID      string      `json:"id"`
-	Code    string      `json:"code"`
-	Link    string      `json:"type"`
-	Title   string      `json:"title"`
-	Status  uint16      `json:"status"`
-	Created string      `json:"created"`
-	Detail  string      `json:"detail,omitempty"`
-	Events  interface{} `json:"events,omitempty"`
+	ID      string                 `json:"id"`
+	Code    string                 `json:"code"`
+	Link    string                 `json:"type"`
+	Title   string                 `json:"title"`
+	Status  uint16                 `json:"status"`
+	Created string                 `json:"created"`
+	Detail  string                 `json:"detail,omitempty"`
+	Trace   string                 `json:"trace,omitempty"`
+	Params  []*InvalidParam        `json:"invalid-params,omitempty"`
+	Extra   map[string]interface{} `json:"-"`
+}
+
+// InvalidParam - некорректный параметр запроса и причина
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// rfcFields - RFC без своих методов сериализации
+type rfcFields RFC
+
+// Члены RFC, которые нельзя переопределить в Extra
+var rfcMembers = map[string]bool{
+	"id": true, "code": true, "type": true, "title": true, "status": true,
+	"created": true, "detail": true, "trace": true, "invalid-params": true,
+}
+
+func (r RFC) MarshalJSON() (_ []byte, err error) {
+	var buf []byte
+
+	if buf, err = json.Marshal(rfcFields(r)); err != nil || len(r.Extra) == 0 {
+		return buf, err
+	}
+
+	obj := make(map[string]json.RawMessage, len(rfcMembers)+len(r.Extra))
+
+	if err = json.Unmarshal(buf, &obj); err != nil {
+		return nil, err
+	}
+
+	for key, val := range r.Extra {
+		if rfcMembers[key] {
+			continue
+		}
+
+		if obj[key], err = json.Marshal(val); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(obj)
+}
+
+func (r *RFC) UnmarshalJSON(buf []byte) (err error) {
+	var obj map[string]json.RawMessage
+
+	if err = json.Unmarshal(buf, (*rfcFields)(r)); err != nil {
+		return err
+	}
+
+	if err = json.Unmarshal(buf, &obj); err != nil {
+		return err
+	}
+
+	r.Extra = nil
+
+	for key, raw := range obj {
+		var val interface{}
+
+		if rfcMembers[key] {
+			continue
+		}
+
+		if err = json.Unmarshal(raw, &val); err != nil {
+			return err
+		}
+
+		if r.Extra == nil {
+			r.Extra = make(map[string]interface{}, len(obj))
+		}
+
+		r.Extra[key] = val
+	}
+
+	return nil
 }
 
 type ViewMonitoring struct {
@@ -53,14 +149,46 @@ type ViewMonitoringEntry struct {
 	Debug  map[string]string `json:"debug,omitempty"`
 }
 
-func (r *Report) AsRFC() *RFC {
+// Brief - краткая копия отчета, только код, статус и заголовок, без цепочки ошибок
+func (r *Report) Brief() *Report {
+	return &Report{
+		ID:      r.ID,
+		Code:    r.Code,
+		Link:    r.Link,
+		Title:   r.Title,
+		Status:  r.Status,
+		Created: r.Created,
+		Service: r.Service,
+		titles:  r.titles,
+	}
+}
+
+// replaceEntry - замена последней записи с таким текстом, если у нее нет своей отладки
+func (r *Report) replaceEntry(text string, ent *ReportEntry) {
+	for i := len(r.Entries) - 1; i >= 0; i-- {
+		if r.Entries[i].Text == text && r.Entries[i].Debug == nil {
+			r.Entries[i] = ent
+			return
+		}
+	}
+}
+
+/*
+	AsRFC - представление отчета в формате RFC7807.
+
+	* accept - значение Accept-Language, по нему выбирается язык заголовка, если перевода нет - заголовок отчета
+	* Детализация и некорректные параметры есть только у отчетов со статусом ниже 500
+*/
+func (r *Report) AsRFC(accept ...string) *RFC {
 	rfc := &RFC{
 		ID:      r.ID,
 		Code:    r.Code,
 		Created: r.Created.Format(time.RFC3339Nano),
 		Link:    r.Link,
-		Title:   r.Title,
+		Title:   localize(strings.Join(accept, ","), r.Title, r.titles),
 		Status:  r.Status,
+		Trace:   r.Trace,
+		Extra:   r.Extra,
 	}
 
 	if rfc.Link == "" {
@@ -76,6 +204,7 @@ func (r *Report) AsRFC() *RFC {
 		}
 
 		rfc.Detail = strings.Join(parts, " => ")
+		rfc.Params = r.Params
 	}
 
 	return rfc
@@ -0,0 +1,65 @@
+package journal
+
+import (
+	"github.com/shestakovda/errx"
+	"github.com/shestakovda/journal/crash"
+)
+
+// exportEntries - выгрузка записей, отчеты об ошибках по всем записям загружаются одним пакетом
+func exportEntries(crf crash.Factory, mods []Model, mode ExportMode) (res []*Entry, err error) {
+	var ids, miss []string
+	var crms []crash.Model
+
+	uniq := make(map[string]bool)
+	res = make([]*Entry, len(mods))
+
+	for i := range mods {
+		if res[i], err = mods[i].Export(false); err != nil {
+			return nil, err
+		}
+
+		if mode == ExportNoCrash {
+			continue
+		}
+
+		for _, stg := range res[i].Chain {
+			if stg.Type == ModelTypeCrash.ID() && !uniq[stg.EnID] {
+				uniq[stg.EnID] = true
+				ids = append(ids, stg.EnID)
+			}
+		}
+	}
+
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	if crms, miss, err = crf.ByIDs(ids...); err != nil {
+		return nil, ErrSelect.WithReason(err)
+	}
+
+	if len(miss) > 0 {
+		return nil, ErrSelect.WithReason(crash.ErrNotFound).WithDebug(errx.Debug{
+			"Отчеты": miss,
+		})
+	}
+
+	// Все отчеты найдены, поэтому идут в том же порядке, что и ids
+	reps := make(map[string]*crash.Report, len(crms))
+
+	for i := range crms {
+		if reps[ids[i]] = crms[i].Export(); mode == ExportCrashBrief {
+			reps[ids[i]] = reps[ids[i]].Brief()
+		}
+	}
+
+	for i := range res {
+		for _, stg := range res[i].Chain {
+			if stg.Type == ModelTypeCrash.ID() {
+				stg.Fail = reps[stg.EnID]
+			}
+		}
+	}
+
+	return res, nil
+}
@@ -1,6 +1,7 @@
 package journal
 
 import (
+	"context"
 	"encoding/binary"
 	"strconv"
 	"time"
@@ -59,6 +60,10 @@ func (f *fdbFactory) New() Model {
 	}
 }
 
+func (f *fdbFactory) Export(mode ExportMode, mods ...Model) ([]*Entry, error) {
+	return exportEntries(crash.NewFactoryFDB(f.db), mods, mode)
+}
+
 func (f *fdbFactory) Cursor(id string) (_ Cursor, err error) {
 	var uid typex.UUID
 
@@ -105,13 +110,50 @@ func (f *fdbFactory) ByID(id string) (_ Model, err error) {
 	return m, nil
 }
 
+func (f *fdbFactory) ByIDs(ids ...string) (res []Model, miss []string, err error) {
+	var uids []typex.UUID
+
+	if uids, err = parseIDs(ids); err != nil {
+		return nil, nil, err
+	}
+
+	recs := make([]fdbx.Record, len(uids))
+	lost := make(map[fdbx.Record]bool)
+
+	for i := range uids {
+		recs[i] = &fdbModel{id: uids[i], fac: f}
+	}
+
+	// Все чтения запускаются сразу, а ненайденные записи просто отмечаем
+	onNotFound := func(rec fdbx.Record) error {
+		lost[rec] = true
+		return nil
+	}
+
+	if err = f.db.Load(onNotFound, recs...); err != nil {
+		return nil, nil, ErrSelect.WithReason(err).WithDebug(errx.Debug{
+			"IDs": ids,
+		})
+	}
+
+	res = make([]Model, 0, len(recs))
+
+	for i := range recs {
+		if lost[recs[i]] {
+			miss = append(miss, ids[i])
+			continue
+		}
+
+		res = append(res, recs[i].(*fdbModel))
+	}
+
+	return res, miss, nil
+}
+
 func (f *fdbFactory) ByModel(mtp ModelType, mid string) (res []Model, err error) {
 	var recs []fdbx.Record
 
-	query := make([]byte, 2+len(mid))
-	binary.BigEndian.PutUint16(query[:2], uint16(mtp.ID()))
-	copy(query[2:], fdbx.S2B(mid))
-
+	query := fdbModelKey(mtp, mid)
 	rtp := fdbx.RecordType{ID: IndexJournalEntity, Ver: verJournalV1, New: f.newRecord}
 
 	if recs, err = f.db.Select(rtp, fdbx.Query(query)); err != nil {
@@ -121,29 +163,56 @@ func (f *fdbFactory) ByModel(mtp ModelType, mid string) (res []Model, err error)
 		})
 	}
 
-	return f.recs2list(recs), nil
+	return f.recs2list(fdbKeepModel(recs, mtp, mid)), nil
+}
+
+func (f *fdbFactory) ByModelCount(mtp ModelType, mid string) (_ uint64, err error) {
+	var recs []fdbx.Record
+
+	// По ключам индекса в этой версии не отличить модели, идентификатор которых - начало другого
+	rtp := fdbx.RecordType{ID: IndexJournalEntity, Ver: verJournalV1, New: f.newRecord}
+
+	if recs, err = f.db.Select(rtp, fdbx.Query(fdbModelKey(mtp, mid))); err != nil {
+		return 0, errx.ErrInternal.WithReason(err).WithDebug(errx.Debug{
+			"Тип модели":    mtp.String(),
+			"Идентификатор": mid,
+		})
+	}
+
+	// Одна запись может ссылаться на модель несколько раз
+	recs = fdbKeepModel(recs, mtp, mid)
+	uniq := make(map[string]struct{}, len(recs))
+
+	for i := range recs {
+		uniq[recs[i].FdbxID()] = struct{}{}
+	}
+
+	return uint64(len(uniq)), nil
 }
 
 func (f *fdbFactory) ByDate(from, to time.Time, page uint, services ...string) (_ Cursor, err error) {
+	return f.ByDateOrder(OrderDesc, from, to, page, services...)
+}
+
+func (f *fdbFactory) ByModelDate(mtp ModelType, mid string,
+	from, to time.Time, page uint, services ...string) (_ Cursor, err error) {
+	return f.ByModelDateOrder(OrderDesc, mtp, mid, from, to, page, services...)
+}
+
+func (f *fdbFactory) ByDateOrder(ord Order, from, to time.Time, page uint, services ...string) (_ Cursor, err error) {
+	if err = checkOrder(ord); err != nil {
+		return nil, err
+	}
+
 	res := &fdbCursor{fac: f}
 	rtp := fdbx.RecordType{ID: IndexJournalStart, Ver: verJournalV1, New: f.newRecord}
-	qto := fdbx.To(crash.Unix(to))
-	qfrom := fdbx.From(crash.Unix(from))
-
-	if res.Cursor, err = f.cn.Cursor(rtp, qfrom, qto, fdbx.Reverse(), fdbx.Page(page),
-		fdbx.Filter(func(record fdbx.Record) (bool, error) {
-			if len(services) != 0 {
-				model := record.(*fdbModel)
-
-				for i := range services {
-					if model.service == services[i] {
-						return true, nil
-					}
-				}
-				return false, nil
-			}
-			return true, nil
-		})); err != nil {
+	opts := []fdbx.Option{fdbx.From(crash.Unix(from)), fdbx.To(crash.Unix(to)), fdbx.Page(page), fdbServices(services)}
+
+	if ord == OrderDesc {
+		opts = append(opts, fdbx.Reverse())
+	}
+
+	if res.Cursor, err = f.cn.Cursor(rtp, opts...); err != nil {
 		return nil, errx.ErrInternal.WithReason(err).WithDebug(errx.Debug{
 			"От момента":      from.UTC().Format(time.RFC3339Nano),
 			"До момента":      to.UTC().Format(time.RFC3339Nano),
@@ -154,30 +223,27 @@ func (f *fdbFactory) ByDate(from, to time.Time, page uint, services ...string) (
 	return res, nil
 }
 
-func (f *fdbFactory) ByModelDate(mtp ModelType, mid string,
+func (f *fdbFactory) ByModelDateOrder(ord Order, mtp ModelType, mid string,
 	from, to time.Time, page uint, services ...string) (_ Cursor, err error) {
-	var mtpb [2]byte
-	binary.BigEndian.PutUint16(mtpb[:], uint16(mtp.ID()))
+	if err = checkOrder(ord); err != nil {
+		return nil, err
+	}
 
 	res := &fdbCursor{fac: f}
+	key := fdbModelKey(mtp, mid)
 	rtp := fdbx.RecordType{ID: IndexJournalEntity, Ver: verJournalV1, New: f.newRecord}
-	qto := fdbx.To(Concat(mtpb[:], fdbx.S2B(mid), crash.Unix(to)))
-	qfrom := fdbx.From(Concat(mtpb[:], fdbx.S2B(mid), crash.Unix(from)))
-
-	if res.Cursor, err = f.cn.Cursor(rtp, qfrom, qto, fdbx.Reverse(), fdbx.Page(page),
-		fdbx.Filter(func(record fdbx.Record) (bool, error) {
-			if len(services) != 0 {
-				model := record.(*fdbModel)
-
-				for i := range services {
-					if model.service == services[i] {
-						return true, nil
-					}
-				}
-				return false, nil
-			}
-			return true, nil
-		})); err != nil {
+	opts := []fdbx.Option{
+		fdbx.From(Concat(key, crash.Unix(from))),
+		fdbx.To(Concat(key, crash.Unix(to))),
+		fdbx.Page(page),
+		fdbFilter(fdbHasModel(mtp, mid), services),
+	}
+
+	if ord == OrderDesc {
+		opts = append(opts, fdbx.Reverse())
+	}
+
+	if res.Cursor, err = f.cn.Cursor(rtp, opts...); err != nil {
 		return nil, errx.ErrInternal.WithReason(err).WithDebug(errx.Debug{
 			"Тип модели":      mtp.String(),
 			"Идентификатор":   mid,
@@ -190,6 +256,62 @@ func (f *fdbFactory) ByModelDate(mtp ModelType, mid string,
 	return res, nil
 }
 
+// ByCrashCode - в этой версии коды ошибок не индексируются
+func (f *fdbFactory) ByCrashCode(Order, string, time.Time, time.Time, uint, ...string) (Cursor, error) {
+	return nil, ErrNotSupported.WithDebug(errx.Debug{"Индекс": "по коду ошибки"})
+}
+
+// ByStatus - в этой версии статусы ошибок не индексируются
+func (f *fdbFactory) ByStatus(Order, uint8, time.Time, time.Time, uint, ...string) (Cursor, error) {
+	return nil, ErrNotSupported.WithDebug(errx.Debug{"Индекс": "по статусу ошибки"})
+}
+
+// SeekSlow - в этой версии длительность записей не индексируется
+func (f *fdbFactory) SeekSlow(SlowOrder, time.Duration, time.Time, time.Time, string, ...string) (Cursor, error) {
+	return nil, ErrNotSupported.WithDebug(errx.Debug{"Индекс": "по длительности"})
+}
+
+// ByTemplate - в этой версии шаблоны отметок не хранятся
+func (f *fdbFactory) ByTemplate(Order, string, time.Time, time.Time, uint, ...string) (Cursor, error) {
+	return nil, ErrNotSupported.WithDebug(errx.Debug{"Индекс": "по шаблону отметки"})
+}
+
+// Search - в этой версии тексты этапов не индексируются
+func (f *fdbFactory) Search(string, time.Time, time.Time, uint, ...string) (Cursor, error) {
+	return nil, ErrNotSupported.WithDebug(errx.Debug{"Индекс": "по тексту этапов"})
+}
+
+func (f *fdbFactory) Aggregate(ctx context.Context, q *AggQuery) (_ []*Series, err error) {
+	var cur Cursor
+	var agg *aggregator
+
+	if agg, err = newAggregator(q); err != nil {
+		return nil, err
+	}
+
+	if cur, err = f.SeekDate(OrderAsc, q.From, q.To, ""); err != nil {
+		return nil, err
+	}
+
+	return agg.scan(ctx, cur)
+}
+
+func (f *fdbFactory) SeekDate(ord Order, from, to time.Time, token string, services ...string) (Cursor, error) {
+	return newFdbSeekCursor(f, ord, IndexJournalStart, nil, from, to, token, services)
+}
+
+func (f *fdbFactory) SeekModelDate(ord Order, mtp ModelType, mid string,
+	from, to time.Time, token string, services ...string) (_ Cursor, err error) {
+	var cur *fdbSeekCursor
+
+	if cur, err = newFdbSeekCursor(f, ord, IndexJournalEntity, fdbModelKey(mtp, mid), from, to, token, services); err != nil {
+		return nil, err
+	}
+
+	cur.keep = fdbHasModel(mtp, mid)
+	return cur, nil
+}
+
 func (f *fdbFactory) recs2list(recs []fdbx.Record) []Model {
 	res := make([]Model, len(recs))
 	for i := range recs {
@@ -288,19 +410,43 @@ func (m *fdbModel) Export(withCrash bool) (e *Entry, err error) {
 }
 
 func (m *fdbModel) ExportAPI(log Provider) *API {
+	return m.exportAPI(getAPIOptions(nil))
+}
+
+func (m *fdbModel) ExportAPIWithOptions(log Provider, args ...APIOption) (*API, error) {
+	opts := getAPIOptions(args)
+	api := m.exportAPI(opts)
+
+	if err := exportCrashAPI(crash.NewFactoryFDB(m.fac.db), opts.fails); err != nil {
+		return nil, err
+	}
+
+	return api, nil
+}
+
+func (m *fdbModel) exportAPI(opts *apiOptions) *API {
 	var name string
+	var wait time.Duration
 
-	stages := make([]*StageAPI, len(m.chain))
+	stages := make([]*StageAPI, 0, len(m.chain))
 
 	for i := range m.chain {
-		if i == 0 {
-			name = m.chain[i].text
+		// Время скрытых отметок переходит к следующей, чтобы не терялось
+		if wait += time.Duration(m.chain[i].wait); opts.skip(int(m.chain[i].verb)) {
+			continue
 		}
 
-		stages[i] = &StageAPI{
+		stg := opts.stage(&StageAPI{
 			Name: m.chain[i].text,
-			Wait: time.Duration(m.chain[i].wait),
+			Wait: wait,
+		}, getType(int(m.chain[i].enTP)), m.chain[i].enID)
+		wait = 0
+
+		if len(stages) == 0 {
+			name = stg.Name
 		}
+
+		stages = append(stages, stg)
 	}
 
 	return &API{
@@ -363,17 +509,23 @@ func (m *fdbModel) FdbxType() fdbx.RecordType {
 }
 
 func (m *fdbModel) FdbxIndex(idx fdbx.Indexer) error {
-	var entp [2]byte
-
 	start := crash.Unix(time.Unix(0, int64(m.start)))
+	mids := make(map[string]struct{}, len(m.chain))
 
 	idx.Grow(8 + 36*len(m.chain))
 	idx.Index(IndexJournalStart, start)
 
+	// Одинаковые модели в одной записи индексируем один раз
 	for _, stage := range m.chain {
-		if stage.enID != "" {
-			binary.BigEndian.PutUint16(entp[:], stage.enTP)
-			idx.Index(IndexJournalEntity, Concat(entp[:], fdbx.S2B(stage.enID), start))
+		if stage.enID == "" {
+			continue
+		}
+
+		pref := fdbModelPrefix(stage.enTP, stage.enID)
+
+		if _, ok := mids[string(pref)]; !ok {
+			mids[string(pref)] = struct{}{}
+			idx.Index(IndexJournalEntity, Concat(pref, start))
 		}
 	}
 
@@ -427,6 +579,31 @@ func (m *fdbModel) FdbxUnmarshal(buf []byte) error {
 	return nil
 }
 
+func (m *fdbModel) ExportSummary() *Summary {
+	v := &Summary{
+		ID:      m.id.Hex(),
+		Service: m.service,
+		Start:   time.Unix(0, int64(m.start)).UTC(),
+		Total:   time.Duration(m.total),
+	}
+
+	if len(m.chain) > 0 {
+		v.Name = m.chain[0].text
+	}
+
+	for i := range m.chain {
+		if int(m.chain[i].enTP) == ModelTypeCrash.ID() {
+			v.Fail = true
+		}
+	}
+
+	return v
+}
+
+func (m *fdbModel) token() string {
+	return newToken(time.Unix(0, int64(m.start)), m.id)
+}
+
 func (m *fdbModel) setID(id string) (err error) {
 	if m.id, err = typex.ParseUUID(id); err != nil {
 		return ErrValidate.WithReason(err).WithDetail("Некорректный формат идентификатора")
@@ -438,27 +615,41 @@ func (m *fdbModel) setID(id string) (err error) {
 type fdbCursor struct {
 	fdbx.Cursor
 	fac *fdbFactory
+	tok string
 }
 
-func (f fdbCursor) ID() string  { return f.FdbxID() }
-func (f fdbCursor) Empty() bool { return f.Cursor.Empty() }
-func (f fdbCursor) NextPage(size uint, services ...string) (_ []Model, err error) {
-	var recs []fdbx.Record
+func (f *fdbCursor) ID() string    { return f.FdbxID() }
+func (f *fdbCursor) Empty() bool   { return f.Cursor.Empty() }
+func (f *fdbCursor) Token() string { return f.tok }
+func (f *fdbCursor) Close() (err error) {
+	if err = f.Cursor.Close(); err != nil {
+		return errx.ErrInternal.WithReason(err).WithDebug(errx.Debug{
+			"ID курсора": f.FdbxID(),
+		})
+	}
 
-	if err = f.ApplyOpts(fdbx.Page(size),
-		fdbx.Filter(func(record fdbx.Record) (bool, error) {
-			if len(services) != 0 {
-				model := record.(*fdbModel)
+	return nil
+}
+func (f *fdbCursor) Each(ctx context.Context, size uint, fn func(Model) error) error {
+	return eachModel(ctx, f, size, fn)
+}
 
-				for i := range services {
-					if model.service == services[i] {
-						return true, nil
-					}
-				}
-				return false, nil
-			}
-			return true, nil
-		})); err != nil {
+func (f *fdbCursor) Stream(ctx context.Context, size uint) (<-chan Model, <-chan error) {
+	return streamModels(ctx, f, size)
+}
+
+func (f *fdbCursor) NextExport(size uint, mode ExportMode, services ...string) ([]*Entry, error) {
+	return nextExport(f, crash.NewFactoryFDB(f.fac.db), size, mode, services)
+}
+
+func (f *fdbCursor) NextSummary(size uint, services ...string) ([]*Summary, error) {
+	return nextSummary(f, size, services)
+}
+
+func (f *fdbCursor) NextPage(size uint, services ...string) (_ []Model, err error) {
+	var recs []fdbx.Record
+
+	if err = f.ApplyOpts(fdbx.Page(size), fdbServices(services)); err != nil {
 		return nil, errx.ErrInternal.WithReason(err).WithDebug(errx.Debug{
 			"ID курсора": f.FdbxID(),
 		})
@@ -470,9 +661,214 @@ func (f fdbCursor) NextPage(size uint, services ...string) (_ []Model, err error
 		})
 	}
 
+	if len(recs) > 0 {
+		f.tok = recs[len(recs)-1].(*fdbModel).token()
+	}
+
 	return f.fac.recs2list(recs), nil
 }
 
+func newFdbSeekCursor(
+	fac *fdbFactory,
+	ord Order,
+	idx uint16,
+	pref []byte,
+	from, to time.Time,
+	token string,
+	services []string,
+) (cur *fdbSeekCursor, err error) {
+	if err = checkOrder(ord); err != nil {
+		return nil, err
+	}
+
+	cur = &fdbSeekCursor{
+		ord:  ord,
+		idx:  idx,
+		fac:  fac,
+		pref: pref,
+		from: Concat(pref, crash.Unix(from)),
+		to:   Concat(pref, crash.Unix(to)),
+		srvs: services,
+	}
+
+	if err = cur.seek(token); err != nil {
+		return nil, err
+	}
+
+	return cur, nil
+}
+
+// fdbSeekCursor - перебор по индексу без сохранения курсора, позиция передается маркером
+type fdbSeekCursor struct {
+	empty bool
+
+	ord  Order
+	idx  uint16
+	tok  string
+	fac  *fdbFactory
+	pref []byte
+	from []byte
+	to   []byte
+	srvs []string
+	keep func(*fdbModel) bool
+}
+
+func (c *fdbSeekCursor) ID() string    { return "" }
+func (c *fdbSeekCursor) Empty() bool   { return c.empty }
+func (c *fdbSeekCursor) Token() string { return c.tok }
+func (c *fdbSeekCursor) Close() error  { c.empty = true; return nil }
+func (c *fdbSeekCursor) Each(ctx context.Context, size uint, fn func(Model) error) error {
+	return eachModel(ctx, c, size, fn)
+}
+
+func (c *fdbSeekCursor) Stream(ctx context.Context, size uint) (<-chan Model, <-chan error) {
+	return streamModels(ctx, c, size)
+}
+
+func (c *fdbSeekCursor) NextExport(size uint, mode ExportMode, services ...string) ([]*Entry, error) {
+	return nextExport(c, crash.NewFactoryFDB(c.fac.db), size, mode, services)
+}
+
+func (c *fdbSeekCursor) NextSummary(size uint, services ...string) ([]*Summary, error) {
+	return nextSummary(c, size, services)
+}
+
+func (c *fdbSeekCursor) NextPage(size uint, services ...string) (_ []Model, err error) {
+	var recs []fdbx.Record
+
+	if c.empty {
+		return nil, nil
+	}
+
+	rtp := fdbx.RecordType{ID: c.idx, Ver: verJournalV1, New: c.fac.newRecord}
+	opts := []fdbx.Option{fdbx.From(c.from), fdbx.To(c.to), fdbx.Limit(size), fdbFilter(c.keep, c.srvs, services)}
+
+	if c.ord == OrderDesc {
+		opts = append(opts, fdbx.Reverse())
+	}
+
+	if recs, err = c.fac.db.Select(rtp, opts...); err != nil {
+		return nil, errx.ErrInternal.WithReason(err).WithDebug(errx.Debug{
+			"Маркер": c.tok,
+		})
+	}
+
+	if len(recs) < int(size) {
+		c.empty = true
+	}
+
+	if len(recs) > 0 {
+		if err = c.seek(recs[len(recs)-1].(*fdbModel).token()); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.fac.recs2list(recs), nil
+}
+
+// seek - сдвиг границы интервала за последнюю загруженную запись
+func (c *fdbSeekCursor) seek(token string) (err error) {
+	var start time.Time
+	var uid typex.UUID
+
+	if start, uid, err = parseToken(token); err != nil || uid == nil {
+		return err
+	}
+
+	// Ключ индекса в этой версии - значение, идентификатор строкой и его длина
+	rid := fdbx.S2B(uid.Hex())
+	key := Concat(c.pref, crash.Unix(start), rid, []byte{byte(len(rid))})
+
+	c.tok = token
+
+	if c.ord == OrderDesc {
+		if c.to = keyBefore(key); c.to == nil {
+			c.empty = true
+		}
+		return nil
+	}
+
+	c.from = Concat(key, []byte{0x00})
+	return nil
+}
+
+// fdbModelKey - префикс ключа индекса по модели
+func fdbModelKey(mtp ModelType, mid string) []byte {
+	return fdbModelPrefix(uint16(mtp.ID()), mid)
+}
+
+// fdbModelPrefix - тип и идентификатор модели, сразу за ним в ключе индекса идет время старта записи
+func fdbModelPrefix(mtp uint16, mid string) []byte {
+	var entp [2]byte
+	binary.BigEndian.PutUint16(entp[:], mtp)
+	return Concat(entp[:], fdbx.S2B(mid))
+}
+
+/*
+	fdbHasModel - проверка, что запись ссылается на модель.
+
+	* Идентификатор модели в ключе индекса ничем не отделен от времени, поэтому по префиксу
+	* попадают и модели, идентификатор которых начинается так же, их отбрасываем по самой записи
+*/
+func fdbHasModel(mtp ModelType, mid string) func(*fdbModel) bool {
+	entp := uint16(mtp.ID())
+
+	return func(model *fdbModel) bool {
+		for _, stage := range model.chain {
+			if stage.enTP == entp && stage.enID == mid {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// fdbKeepModel - только записи, которые ссылаются на модель
+func fdbKeepModel(recs []fdbx.Record, mtp ModelType, mid string) []fdbx.Record {
+	keep := fdbHasModel(mtp, mid)
+	res := recs[:0]
+
+	for i := range recs {
+		if keep(recs[i].(*fdbModel)) {
+			res = append(res, recs[i])
+		}
+	}
+
+	return res
+}
+
+// fdbServices - фильтр записей по спискам сервисов, запись должна быть в каждом непустом списке
+func fdbServices(lists ...[]string) fdbx.Option {
+	return fdbFilter(nil, lists...)
+}
+
+// fdbFilter - фильтр записей по сервисам и дополнительному условию, если оно есть
+func fdbFilter(keep func(*fdbModel) bool, lists ...[]string) fdbx.Option {
+	return fdbx.Filter(func(record fdbx.Record) (bool, error) {
+		model := record.(*fdbModel)
+
+		if keep != nil && !keep(model) {
+			return false, nil
+		}
+
+		for _, services := range lists {
+			if len(services) != 0 && !fdbHasService(services, model.service) {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}
+
+func fdbHasService(services []string, service string) bool {
+	for i := range services {
+		if services[i] == service {
+			return true
+		}
+	}
+	return false
+}
+
 func Concat(parts ...[]byte) []byte {
 	var size, from, to int
 
@@ -490,3 +886,20 @@ func Concat(parts ...[]byte) []byte {
 
 	return res
 }
+
+// parseIDs - разбор списка идентификаторов для пакетной загрузки, пустой список тоже ошибка
+func parseIDs(ids []string) (uids []typex.UUID, err error) {
+	if len(ids) == 0 {
+		return nil, ErrValidate.WithDetail("Не указаны идентификаторы")
+	}
+
+	uids = make([]typex.UUID, len(ids))
+
+	for i := range ids {
+		if uids[i], err = typex.ParseUUID(ids[i]); err != nil {
+			return nil, ErrValidate.WithReason(err).WithDetail("Некорректный формат идентификатора")
+		}
+	}
+
+	return uids, nil
+}
@@ -158,6 +158,14 @@ func (s *FdbSuite) TestByID() {
 			s.True(errx.Is(err, errx.ErrBadRequest))
 		}
 
+		if _, _, err = fac.ByIDs(); s.Error(err) {
+			s.True(errx.Is(err, ErrValidate))
+		}
+
+		if _, _, err = fac.ByIDs("unknown"); s.Error(err) {
+			s.True(errx.Is(err, ErrValidate))
+		}
+
 		uid := typex.NewUUID()
 
 		fdb.FLoad = func(fdbx.RecordHandler, ...fdbx.Record) error { return assert.AnError }
@@ -184,3 +192,63 @@ func (s *FdbSuite) TestByID() {
 		return nil
 	}))
 }
+
+func (s *FdbSuite) TestModelIndex() {
+	fdb := new(fdbx.MockConn)
+
+	s.NoError(fdb.Tx(func(db fdbx.DB) (err error) {
+		fdb.FAt = func(uint16) fdbx.DB { return db }
+
+		fac := NewFactoryFDB(fdb, db)
+		mod := fac.New().(*fdbModel)
+		mod.chain = []*fdbStage{
+			{enTP: 42, enID: "eventID"},
+			{enTP: 42, enID: "eventID"},
+			{enTP: 42, enID: "eventID2"},
+		}
+
+		// Одна и та же модель индексируется один раз, ключи в прежнем формате
+		idx := &testIndexer{keys: make(map[uint16][][]byte)}
+		start := crash.Unix(time.Unix(0, int64(mod.start)))
+
+		if s.NoError(mod.FdbxIndex(idx)) && s.Len(idx.keys[IndexJournalEntity], 2) {
+			s.Contains(idx.keys[IndexJournalEntity], Concat(fdbModelKey(testModelType(42), "eventID"), start))
+			s.Contains(idx.keys[IndexJournalEntity], Concat(fdbModelKey(testModelType(42), "eventID2"), start))
+		}
+
+		// По префиксу попадают и более длинные идентификаторы, их отбрасываем по записи
+		mod2 := fac.New().(*fdbModel)
+		mod2.chain = []*fdbStage{{enTP: 42, enID: "eventID2"}}
+
+		fdb.FSelect = func(fdbx.RecordType, ...fdbx.Option) ([]fdbx.Record, error) {
+			return []fdbx.Record{mod, mod2, mod}, nil
+		}
+
+		// Записи, проиндексированные несколько раз, считаются один раз
+		if cnt, err := fac.ByModelCount(testModelType(42), "eventID"); s.NoError(err) {
+			s.Equal(uint64(1), cnt)
+		}
+
+		if list, err := fac.ByModel(testModelType(42), "eventID"); s.NoError(err) && s.Len(list, 2) {
+			s.Equal(mod, list[0])
+			s.Equal(mod, list[1])
+		}
+
+		return nil
+	}))
+}
+
+type testIndexer struct {
+	keys map[uint16][][]byte
+}
+
+func (idx *testIndexer) Grow(int) {}
+
+func (idx *testIndexer) Index(id uint16, value []byte) {
+	idx.keys[id] = append(idx.keys[id], value)
+}
+
+type testModelType int
+
+func (mt testModelType) ID() int        { return int(mt) }
+func (mt testModelType) String() string { return "test" }
@@ -1,24 +1,34 @@
 package journal
 
 import (
+	"context"
+	"time"
+
 	"github.com/shestakovda/errx"
 	"github.com/shestakovda/fdbx/v2"
 	"github.com/shestakovda/fdbx/v2/orm"
 	"github.com/shestakovda/typex"
 )
 
-func newFdbxCursor(fac *fdbxFactory, qid string, que orm.Query) *fdbxCursor {
-	return &fdbxCursor{
-		qid: qid,
-		que: que,
-		fac: fac,
+func newFdbxCursor(fac *fdbxFactory, qid string, que orm.Query, services []string) *fdbxCursor {
+	cur := &fdbxCursor{
+		qid:       qid,
+		srvs:      services,
+		fdbxPager: newFdbxPager(fac),
 	}
+
+	cur.que = que.Where(cur.filter)
+	return cur
 }
 
 func loadFdbxCursor(fac *fdbxFactory, qid string) (cur *fdbxCursor, err error) {
 	cur = &fdbxCursor{
-		qid: qid,
-		fac: fac,
+		qid:       qid,
+		fdbxPager: newFdbxPager(fac),
+	}
+
+	if err = fac.checkCursorTTL(qid); err != nil {
+		return nil, err
 	}
 
 	if cur.que, err = fac.tbl.Cursor(fac.tx, qid); err != nil {
@@ -33,15 +43,26 @@ func loadFdbxCursor(fac *fdbxFactory, qid string) (cur *fdbxCursor, err error) {
 		})
 	}
 
+	cur.que = cur.que.Where(cur.filter)
 	return cur, nil
 }
 
+/*
+	fdbxCursor - перебор по сохраненному запросу orm.
+
+	* srvs - сервисы, указанные при создании курсора, в БД вместе с запросом не сохраняются
+	* page - сервисы, указанные при загрузке текущей страницы, проверяются вместе с srvs
+	* При долгом переборе запрос с позицией переносится в новую транзакцию и обратно
+*/
 type fdbxCursor struct {
+	fdbxPager
 	empty bool
 
-	qid string
-	que orm.Query
-	fac *fdbxFactory
+	qid  string
+	tok  string
+	que  orm.Query
+	srvs []string
+	page orm.Filter
 }
 
 func (c *fdbxCursor) ID() string {
@@ -52,11 +73,56 @@ func (c *fdbxCursor) Empty() bool {
 	return c.empty
 }
 
+func (c *fdbxCursor) Token() string {
+	return c.tok
+}
+
+func (c *fdbxCursor) Close() (err error) {
+	c.empty = true
+
+	if err = c.release(); err != nil {
+		return err
+	}
+
+	if err = c.que.Drop(); err != nil {
+		return errx.ErrInternal.WithReason(err).WithDebug(errx.Debug{
+			"Курсор": c.qid,
+		})
+	}
+
+	if err = c.fac.dropCursorTTL(c.qid); err != nil {
+		return errx.ErrInternal.WithReason(err).WithDebug(errx.Debug{
+			"Курсор": c.qid,
+		})
+	}
+
+	return nil
+}
+
+func (c *fdbxCursor) Each(ctx context.Context, size uint, fn func(Model) error) error {
+	return eachModel(ctx, c, size, fn)
+}
+
+func (c *fdbxCursor) Stream(ctx context.Context, size uint) (<-chan Model, <-chan error) {
+	return streamModels(ctx, c, size)
+}
+
+func (c *fdbxCursor) NextExport(size uint, mode ExportMode, services ...string) ([]*Entry, error) {
+	return nextExport(c, c.fac.crf, size, mode, services)
+}
+
+func (c *fdbxCursor) NextSummary(size uint, services ...string) ([]*Summary, error) {
+	return nextSummary(c, size, services)
+}
+
 func (c *fdbxCursor) NextPage(size uint, services ...string) (res []Model, err error) {
 	var rows []fdbx.Pair
 
+	// Фильтры запроса накапливаются, поэтому сервисы страницы подменяем в своем фильтре
+	c.page = nil
+
 	if len(services) > 0 {
-		c.que = c.que.Where(filterByService(services))
+		c.page = filterByService(services)
 	}
 
 	if rows, err = c.que.Page(int(size)).Next(); err != nil {
@@ -69,10 +135,244 @@ func (c *fdbxCursor) NextPage(size uint, services ...string) (res []Model, err e
 		c.empty = true
 	}
 
-	res = make([]Model, len(rows))
-	for i := range rows {
-		res[i] = loadFdbxModel(c.fac, typex.UUID(rows[i].Key().Bytes()), rows[i].Value())
+	if res = loadFdbxPage(c.fac, rows); len(res) > 0 {
+		c.tok = res[len(res)-1].(*fdbxModel).token()
+	}
+
+	return res, nil
+}
+
+// filter - записи сервисов курсора и текущей страницы
+func (c *fdbxCursor) filter(row fdbx.Pair) (ok bool, err error) {
+	if len(c.srvs) > 0 {
+		if ok, err = filterByService(c.srvs)(row); err != nil || !ok {
+			return ok, err
+		}
+	}
+
+	if c.page != nil {
+		return c.page(row)
+	}
+
+	return true, nil
+}
+
+// renew - запрос вместе с позицией копируется в новую транзакцию, старая остается для выданных записей
+func (c *fdbxCursor) renew() (err error) {
+	src := c.fac
+
+	if err = c.fdbxPager.renew(); err != nil {
+		return err
+	}
+
+	return c.moveQuery(src, c.fac)
+}
+
+// release - позиция запроса возвращается в транзакцию фабрики, чтобы курсор можно было загрузить по идентификатору
+func (c *fdbxCursor) release() (err error) {
+	if !c.renewed() {
+		return nil
+	}
+
+	if err = c.moveQuery(c.fac, c.base); err != nil {
+		c.fdbxPager.release()
+		return err
+	}
+
+	return c.fdbxPager.release()
+}
+
+// moveQuery - копирование сохраненного запроса из одной транзакции в другую и загрузка его там
+func (c *fdbxCursor) moveQuery(src, dst *fdbxFactory) (err error) {
+	var row fdbx.Pair
+	var uid typex.UUID
+
+	dbg := errx.Debug{"Курсор": c.qid}
+
+	if uid, err = typex.ParseUUID(c.qid); err != nil {
+		return errx.ErrInternal.WithReason(err).WithDebug(dbg)
+	}
+
+	key := orm.WrapQueryKey(src.tbl.ID(), fdbx.Bytes2Key(uid))
+
+	if _, err = c.que.Save(); err != nil {
+		return errx.ErrInternal.WithReason(err).WithDebug(dbg)
+	}
+
+	if row, err = src.tx.Select(key); err != nil {
+		return errx.ErrInternal.WithReason(err).WithDebug(dbg)
+	}
+
+	if err = dst.tx.Upsert([]fdbx.Pair{fdbx.NewPair(key, row.Value())}); err != nil {
+		return errx.ErrInternal.WithReason(err).WithDebug(dbg)
+	}
+
+	if c.que, err = dst.tbl.Cursor(dst.tx, c.qid); err != nil {
+		return errx.ErrInternal.WithReason(err).WithDebug(dbg)
+	}
+
+	c.que = c.que.Where(c.filter)
+	return nil
+}
+
+func newFdbxSeekCursor(
+	fac *fdbxFactory,
+	ord Order,
+	idx uint16,
+	pref []byte,
+	from time.Time,
+	last time.Time,
+	token string,
+	services []string,
+) (cur *fdbxSeekCursor, err error) {
+	if err = checkOrder(ord); err != nil {
+		return nil, err
+	}
+
+	cur = &fdbxSeekCursor{
+		ord:       ord,
+		idx:       idx,
+		tok:       token,
+		pref:      pref,
+		from:      Concat(pref, fdbx.Time2Byte(from)),
+		last:      Concat(pref, fdbx.Time2Byte(last)),
+		srvs:      services,
+		fdbxPager: newFdbxPager(fac),
+	}
+
+	if err = cur.seek(token); err != nil {
+		return nil, err
+	}
+
+	return cur, nil
+}
+
+// fdbxSeekCursor - перебор по индексу без сохранения курсора, позиция передается маркером
+type fdbxSeekCursor struct {
+	fdbxPager
+	empty bool
+
+	ord  Order
+	idx  uint16
+	tok  string
+	pref []byte
+	from []byte
+	last []byte
+	srvs []string
+	keep orm.Filter
+}
+
+func (c *fdbxSeekCursor) ID() string {
+	return ""
+}
+
+func (c *fdbxSeekCursor) Empty() bool {
+	return c.empty
+}
+
+func (c *fdbxSeekCursor) Token() string {
+	return c.tok
+}
+
+// Close - курсор нигде не сохранен, поэтому просто прекращаем перебор и завершаем его транзакции
+func (c *fdbxSeekCursor) Close() error {
+	c.empty = true
+	return c.release()
+}
+
+func (c *fdbxSeekCursor) Each(ctx context.Context, size uint, fn func(Model) error) error {
+	return eachModel(ctx, c, size, fn)
+}
+
+func (c *fdbxSeekCursor) Stream(ctx context.Context, size uint) (<-chan Model, <-chan error) {
+	return streamModels(ctx, c, size)
+}
+
+func (c *fdbxSeekCursor) NextExport(size uint, mode ExportMode, services ...string) ([]*Entry, error) {
+	return nextExport(c, c.fac.crf, size, mode, services)
+}
+
+func (c *fdbxSeekCursor) NextSummary(size uint, services ...string) ([]*Summary, error) {
+	return nextSummary(c, size, services)
+}
+
+func (c *fdbxSeekCursor) NextPage(size uint, services ...string) (res []Model, err error) {
+	var rows []fdbx.Pair
+
+	if c.empty {
+		return nil, nil
+	}
+
+	que := c.fac.tbl.Select(c.fac.tx).ByIndexRange(c.idx, fdbx.Bytes2Key(c.from), fdbx.Bytes2Key(c.last))
+
+	if c.ord == OrderDesc {
+		que = que.Reverse()
+	}
+
+	if c.keep != nil {
+		que = que.Where(c.keep)
+	}
+
+	if len(c.srvs) > 0 {
+		que = que.Where(filterByService(c.srvs))
+	}
+
+	if len(services) > 0 {
+		que = que.Where(filterByService(services))
+	}
+
+	// Здесь не используем Next, потому что он сохраняет запрос в БД
+	if rows, err = que.Limit(int(size)).All(); err != nil {
+		return nil, errx.ErrInternal.WithReason(err).WithDebug(errx.Debug{
+			"Маркер":  c.tok,
+			"Сервисы": services,
+		})
+	}
+
+	if len(rows) < int(size) {
+		c.empty = true
+	}
+
+	if res = loadFdbxPage(c.fac, rows); len(res) > 0 {
+		if err = c.seek(res[len(res)-1].(*fdbxModel).token()); err != nil {
+			return nil, err
+		}
 	}
 
 	return res, nil
 }
+
+// seek - сдвиг границы интервала за последнюю загруженную запись
+func (c *fdbxSeekCursor) seek(token string) (err error) {
+	var start time.Time
+	var uid typex.UUID
+
+	if start, uid, err = parseToken(token); err != nil || uid == nil {
+		return err
+	}
+
+	c.tok = token
+	key := Concat(c.pref, fdbx.Time2Byte(start), uid)
+
+	if c.ord == OrderDesc {
+		if c.last = keyBefore(key); c.last == nil {
+			c.empty = true
+		}
+		return nil
+	}
+
+	// Ключи индекса хранятся с суффиксом версии, поэтому пропускаем их все
+	c.from = Concat(key, fdbxKeyTail)
+	return nil
+}
+
+// Суффикс, который больше любой версии ключа в mvcc
+var fdbxKeyTail = []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+
+func loadFdbxPage(fac *fdbxFactory, rows []fdbx.Pair) []Model {
+	res := make([]Model, len(rows))
+	for i := range rows {
+		res[i] = loadFdbxModel(fac, typex.UUID(rows[i].Key().Bytes()), rows[i].Value())
+	}
+	return res
+}
@@ -5,18 +5,20 @@ import (
 	"github.com/shestakovda/fdbx/v2/mvcc"
 )
 
-func newFdbxDriver(dbc db.Connection, journalID, crashID uint16) *fdbxDriver {
+func newFdbxDriver(dbc db.Connection, journalID, crashID uint16, opts ...FdbxOption) *fdbxDriver {
 	return &fdbxDriver{
-		dbc: dbc,
-		cid: crashID,
-		jid: journalID,
+		dbc:  dbc,
+		cid:  crashID,
+		jid:  journalID,
+		opts: opts,
 	}
 }
 
 type fdbxDriver struct {
-	cid uint16
-	jid uint16
-	dbc db.Connection
+	cid  uint16
+	jid  uint16
+	dbc  db.Connection
+	opts []FdbxOption
 }
 
 func (d fdbxDriver) InsertEntry(e *Entry) (err error) {
@@ -27,7 +29,7 @@ func (d fdbxDriver) InsertEntry(e *Entry) (err error) {
 	}
 	defer tx.Cancel()
 
-	if err = newFdbxFactory(tx, d.jid, d.cid).New().Import(e); err != nil {
+	if err = newFdbxFactory(tx, d.jid, d.cid, d.opts...).New().Import(e); err != nil {
 		return ErrInsert.WithReason(err)
 	}
 
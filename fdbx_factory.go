@@ -1,7 +1,9 @@
 package journal
 
 import (
+	"context"
 	"encoding/binary"
+	"hash/fnv"
 	"time"
 
 	"github.com/shestakovda/errx"
@@ -9,12 +11,29 @@ import (
 	"github.com/shestakovda/fdbx/v2/mvcc"
 	"github.com/shestakovda/fdbx/v2/orm"
 	"github.com/shestakovda/journal/crash"
+	"github.com/shestakovda/journal/internal/batch"
 	"github.com/shestakovda/typex"
 )
 
-func newFdbxFactory(tx mvcc.Tx, journalID, crashID uint16) *fdbxFactory {
+/*
+	Области ключей таблицы журнала вне областей orm, все в одном месте, чтобы номера не пересекались.
+
+	* Номера выдаются подряд, новые области добавляются только в конец
+	* Значения сверток хранятся вне mvcc, чтобы увеличивать их атомарно
+*/
+const (
+	nsCursorMeta   byte = 0x10 // Время истечения курсора по идентификатору
+	nsCursorTTL    byte = 0x11 // Очередь курсоров по времени истечения
+	nsCursorSearch byte = 0x12 // Параметры полнотекстового поиска по идентификатору курсора
+	nsRollup       byte = 0x13 // Счетчики корзин сверток по уровням
+	nsRollupName   byte = 0x14 // Реестр названий, попавших в свертки, и их кол-во
+)
+
+func newFdbxFactory(tx mvcc.Tx, journalID, crashID uint16, opts ...FdbxOption) *fdbxFactory {
 	return &fdbxFactory{
 		tx:  tx,
+		cid: crashID,
+		opt: getFdbxOptions(opts),
 		crf: crash.NewFdbxFactory(tx, crashID),
 		tbl: orm.NewTable(journalID, orm.BatchIndex(idxJournal)),
 	}
@@ -22,22 +41,40 @@ func newFdbxFactory(tx mvcc.Tx, journalID, crashID uint16) *fdbxFactory {
 
 type fdbxFactory struct {
 	tx  mvcc.Tx
+	cid uint16
 	tbl orm.Table
+	opt *fdbxOptions
 	crf crash.Factory
 }
 
+// withTx - та же фабрика, но в другой транзакции
+func (f *fdbxFactory) withTx(tx mvcc.Tx) *fdbxFactory {
+	return &fdbxFactory{
+		tx:  tx,
+		cid: f.cid,
+		tbl: f.tbl,
+		opt: f.opt,
+		crf: crash.NewFdbxFactory(tx, f.cid),
+	}
+}
+
 func (f *fdbxFactory) New() Model {
 	return newFdbxModel(f)
 }
 
 func (f *fdbxFactory) ByID(id string) (_ Model, err error) {
-	var row fdbx.Pair
 	var uid typex.UUID
 
 	if uid, err = typex.ParseUUID(id); err != nil {
 		return nil, ErrValidate.WithReason(err).WithDetail("Некорректный формат идентификатора")
 	}
 
+	return f.byUID(uid)
+}
+
+func (f *fdbxFactory) byUID(uid typex.UUID) (_ Model, err error) {
+	var row fdbx.Pair
+
 	if row, err = f.tbl.Select(f.tx).ByID(fdbx.Bytes2Key(uid)).First(); err != nil {
 		dbg := errx.Debug{"ID": uid.Hex()}
 
@@ -51,81 +88,369 @@ func (f *fdbxFactory) ByID(id string) (_ Model, err error) {
 	return loadFdbxModel(f, uid, row.Value()), nil
 }
 
+func (f *fdbxFactory) ByIDs(ids ...string) (res []Model, miss []string, err error) {
+	var lost []int
+	var uids []typex.UUID
+
+	if uids, err = parseIDs(ids); err != nil {
+		return nil, nil, err
+	}
+
+	mods := make([]Model, len(uids))
+
+	if lost, err = batch.Load(len(uids), func(i int) (_ bool, exp error) {
+		if mods[i], exp = f.byUID(uids[i]); errx.Is(exp, ErrNotFound) {
+			return false, nil
+		}
+		return exp == nil, exp
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	res = make([]Model, 0, len(mods)-len(lost))
+
+	for i := range mods {
+		if mods[i] != nil {
+			res = append(res, mods[i])
+		}
+	}
+
+	for _, i := range lost {
+		miss = append(miss, ids[i])
+	}
+
+	return res, miss, nil
+}
+
 func (f *fdbxFactory) ByModel(mtp ModelType, mid string) (res []Model, err error) {
 	var rows []fdbx.Pair
 
-	entp := make([]byte, 4)
-	binary.BigEndian.PutUint32(entp, uint32(mtp.ID()))
-	query := fdbx.String2Key(mid).LPart(entp...)
+	query := fdbx.Bytes2Key(modelKey(mtp, mid))
 
-	if rows, err = f.tbl.Select(f.tx).ByIndex(IndexModel, query).All(); err != nil {
+	if rows, err = f.tbl.Select(f.tx).ByIndex(IndexModel, query).Where(filterByModel(mtp, mid)).All(); err != nil {
 		return nil, errx.ErrInternal.WithReason(err).WithDebug(errx.Debug{
 			"Тип модели":    mtp.String(),
 			"Идентификатор": mid,
 		})
 	}
 
-	res = make([]Model, len(rows))
-	for i := range rows {
-		res[i] = loadFdbxModel(f, typex.UUID(rows[i].Key().Bytes()), rows[i].Value())
+	return loadFdbxPage(f, rows), nil
+}
+
+func (f *fdbxFactory) ByModelCount(mtp ModelType, mid string) (_ uint64, err error) {
+	pref := fdbx.Bytes2Key(modelKey(mtp, mid))
+	tbid := f.tbl.ID()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	keys, errs := f.tx.SeqScan(ctx,
+		mvcc.From(orm.WrapIndexKey(tbid, IndexModel, pref)),
+		mvcc.Last(orm.WrapIndexKey(tbid, IndexModel, pref)),
+	)
+
+	// В значении ключа индекса идентификатор записи, одна запись может ссылаться на модель несколько раз
+	uniq := make(map[string]struct{})
+
+	for key := range keys {
+		if isModelKey(key, pref.Bytes()) {
+			uniq[string(key.Value())] = struct{}{}
+		}
 	}
 
-	return res, nil
+	for err = range errs {
+		if err != nil {
+			return 0, errx.ErrInternal.WithReason(err).WithDebug(errx.Debug{
+				"Тип модели":    mtp.String(),
+				"Идентификатор": mid,
+			})
+		}
+	}
+
+	return uint64(len(uniq)), nil
+}
+
+func (f *fdbxFactory) Export(mode ExportMode, mods ...Model) ([]*Entry, error) {
+	return exportEntries(f.crf, mods, mode)
 }
 
-func (f *fdbxFactory) Cursor(id string) (Cursor, error) {
+func (f *fdbxFactory) Cursor(id string) (_ Cursor, err error) {
+	var cur *fdbxSearchCursor
+
+	// У курсоров поиска свое хранилище позиции
+	if cur, err = loadFdbxSearchCursor(f, id); err != nil {
+		return nil, err
+	}
+
+	if cur != nil {
+		return cur, nil
+	}
+
 	return loadFdbxCursor(f, id)
 }
 
 func (f *fdbxFactory) ByDate(from, last time.Time, page uint, services ...string) (_ Cursor, err error) {
-	var qid string
+	return f.ByDateOrder(OrderDesc, from, last, page, services...)
+}
 
-	que := f.tbl.Select(f.tx).Page(int(page)).Reverse().ByIndexRange(
-		IndexStart,
-		fdbx.Bytes2Key(fdbx.Time2Byte(from)),
-		fdbx.Bytes2Key(fdbx.Time2Byte(last)),
-	)
+func (f *fdbxFactory) ByModelDate(
+	mtp ModelType,
+	mid string,
+	from time.Time,
+	last time.Time,
+	page uint,
+	services ...string,
+) (_ Cursor, err error) {
+	return f.ByModelDateOrder(OrderDesc, mtp, mid, from, last, page, services...)
+}
 
-	if qid, err = que.Save(); err != nil {
+func (f *fdbxFactory) ByDateOrder(ord Order, from, last time.Time, page uint, services ...string) (Cursor, error) {
+	return f.saveCursor(ord, IndexStart, nil, nil, from, last, page, services, errx.Debug{
+		"От момента":      from.UTC().Format(time.RFC3339Nano),
+		"До момента":      last.UTC().Format(time.RFC3339Nano),
+		"Размер страницы": page,
+		"Сервисы":         services,
+	})
+}
+
+func (f *fdbxFactory) ByModelDateOrder(
+	ord Order,
+	mtp ModelType,
+	mid string,
+	from time.Time,
+	last time.Time,
+	page uint,
+	services ...string,
+) (Cursor, error) {
+	return f.saveCursor(ord, IndexModel, modelKey(mtp, mid), filterByModel(mtp, mid), from, last, page, services, errx.Debug{
+		"Тип модели":      mtp.String(),
+		"Идентификатор":   mid,
+		"От момента":      from.UTC().Format(time.RFC3339Nano),
+		"До момента":      last.UTC().Format(time.RFC3339Nano),
+		"Размер страницы": page,
+		"Сервисы":         services,
+	})
+}
+
+func (f *fdbxFactory) ByCrashCode(
+	ord Order,
+	code string,
+	from time.Time,
+	last time.Time,
+	page uint,
+	services ...string,
+) (Cursor, error) {
+	return f.saveCursor(ord, IndexCrashCode, codeKey(code), nil, from, last, page, services, errx.Debug{
+		"Код ошибки":      code,
+		"От момента":      from.UTC().Format(time.RFC3339Nano),
+		"До момента":      last.UTC().Format(time.RFC3339Nano),
+		"Размер страницы": page,
+		"Сервисы":         services,
+	})
+}
+
+func (f *fdbxFactory) ByStatus(
+	ord Order,
+	class uint8,
+	from time.Time,
+	last time.Time,
+	page uint,
+	services ...string,
+) (Cursor, error) {
+	return f.saveCursor(ord, IndexStatus, []byte{class}, nil, from, last, page, services, errx.Debug{
+		"Класс статуса":   class,
+		"От момента":      from.UTC().Format(time.RFC3339Nano),
+		"До момента":      last.UTC().Format(time.RFC3339Nano),
+		"Размер страницы": page,
+		"Сервисы":         services,
+	})
+}
+
+func (f *fdbxFactory) ByTemplate(
+	ord Order,
+	tmpl string,
+	from time.Time,
+	last time.Time,
+	page uint,
+	services ...string,
+) (Cursor, error) {
+	return f.saveCursor(ord, IndexTemplate, templateKey(tmpl), nil, from, last, page, services, errx.Debug{
+		"Шаблон":          tmpl,
+		"От момента":      from.UTC().Format(time.RFC3339Nano),
+		"До момента":      last.UTC().Format(time.RFC3339Nano),
+		"Размер страницы": page,
+		"Сервисы":         services,
+	})
+}
+
+func (f *fdbxFactory) SeekSlow(
+	ord SlowOrder,
+	min time.Duration,
+	from time.Time,
+	last time.Time,
+	token string,
+	services ...string,
+) (Cursor, error) {
+	return newFdbxSlowCursor(f, ord, min, from, last, token, services)
+}
+
+func (f *fdbxFactory) Search(query string, from, last time.Time, page uint, services ...string) (_ Cursor, err error) {
+	cur := newFdbxSearchCursor(f, query, from, last, services)
+
+	if len(cur.scan.Words) == 0 {
+		return nil, ErrValidate.WithDetail("В запросе нет слов для поиска").WithDebug(errx.Debug{
+			"Запрос": query,
+		})
+	}
+
+	// Сохраняем сразу, чтобы курсор был доступен по идентификатору и до загрузки первой страницы
+	if err = cur.save(); err != nil {
 		return nil, errx.ErrInternal.WithReason(err).WithDebug(errx.Debug{
+			"Запрос":          query,
 			"От момента":      from.UTC().Format(time.RFC3339Nano),
 			"До момента":      last.UTC().Format(time.RFC3339Nano),
 			"Размер страницы": page,
 		})
 	}
 
-	return newFdbxCursor(f, qid, que), nil
+	return cur, nil
 }
 
-func (f *fdbxFactory) ByModelDate(
-	mtp ModelType,
-	mid string,
+func (f *fdbxFactory) Aggregate(ctx context.Context, q *AggQuery) (_ []*Series, err error) {
+	var cur Cursor
+	var agg *aggregator
+
+	if agg, err = newAggregator(q); err != nil {
+		return nil, err
+	}
+
+	// Свертки быстрее, но только если их корзины совпадают с точками рядов
+	if lvl := f.rollupFor(q); lvl != nil {
+		return f.aggregateRollup(ctx, agg, lvl)
+	}
+
+	if cur, err = f.SeekDate(OrderAsc, q.From, q.To, ""); err != nil {
+		return nil, err
+	}
+
+	return agg.scan(ctx, cur)
+}
+
+/*
+	saveCursor - сохранение запроса по интервалу индекса в БД вместе со сроком действия курсора.
+
+	* keep - дополнительный фильтр записей, например отбрасывающий чужие модели с тем же префиксом
+	* Фильтры в БД не сохраняются, после загрузки по идентификатору сервисы передают в NextPage
+*/
+func (f *fdbxFactory) saveCursor(
+	ord Order,
+	idx uint16,
+	pref []byte,
+	keep orm.Filter,
 	from time.Time,
 	last time.Time,
 	page uint,
-	_ ...string,
+	services []string,
+	dbg errx.Debug,
 ) (_ Cursor, err error) {
 	var qid string
 
-	entp := make([]byte, 4)
-	binary.BigEndian.PutUint32(entp, uint32(mtp.ID()))
+	if err = checkOrder(ord); err != nil {
+		return nil, err
+	}
 
-	key := fdbx.String2Key(mid).LPart(entp...)
-	que := f.tbl.Select(f.tx).Page(int(page)).Reverse().ByIndexRange(
-		IndexModel,
-		key.RPart(fdbx.Time2Byte(from)...),
-		key.RPart(fdbx.Time2Byte(last)...),
-	)
+	que := f.selectRange(ord, idx, pref, from, last).Page(int(page))
 
 	if qid, err = que.Save(); err != nil {
+		return nil, errx.ErrInternal.WithReason(err).WithDebug(dbg)
+	}
+
+	if err = f.saveCursorTTL(qid); err != nil {
 		return nil, errx.ErrInternal.WithReason(err).WithDebug(errx.Debug{
-			"Тип модели":      mtp.String(),
-			"Идентификатор":   mid,
-			"От момента":      from.UTC().Format(time.RFC3339Nano),
-			"До момента":      last.UTC().Format(time.RFC3339Nano),
-			"Размер страницы": page,
+			"Курсор": qid,
 		})
 	}
 
-	return newFdbxCursor(f, qid, que), nil
+	if keep != nil {
+		que = que.Where(keep)
+	}
+
+	return newFdbxCursor(f, qid, que, services), nil
+}
+
+func (f *fdbxFactory) SeekDate(ord Order, from, last time.Time, token string, services ...string) (Cursor, error) {
+	return newFdbxSeekCursor(f, ord, IndexStart, nil, from, last, token, services)
+}
+
+func (f *fdbxFactory) SeekModelDate(
+	ord Order,
+	mtp ModelType,
+	mid string,
+	from time.Time,
+	last time.Time,
+	token string,
+	services ...string,
+) (Cursor, error) {
+	cur, err := newFdbxSeekCursor(f, ord, IndexModel, modelKey(mtp, mid), from, last, token, services)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cur.keep = filterByModel(mtp, mid)
+	return cur, nil
+}
+
+// selectRange - запрос по интервалу времени в индексе, с префиксом и в нужном порядке
+func (f *fdbxFactory) selectRange(ord Order, idx uint16, pref []byte, from, last time.Time) orm.Query {
+	que := f.tbl.Select(f.tx).ByIndexRange(
+		idx,
+		fdbx.Bytes2Key(Concat(pref, fdbx.Time2Byte(from))),
+		fdbx.Bytes2Key(Concat(pref, fdbx.Time2Byte(last))),
+	)
+
+	if ord == OrderDesc {
+		que = que.Reverse()
+	}
+
+	return que
+}
+
+// codeKey - префикс ключа индекса по коду ошибки, с разделителем, чтобы коды не были префиксами друг друга
+func codeKey(code string) []byte {
+	return Concat([]byte(code), []byte{0})
+}
+
+// templateKey - префикс ключа индекса по шаблону, хэш фиксированной длины, чтобы не хранить длинные шаблоны
+func templateKey(tmpl string) []byte {
+	h := fnv.New64a()
+	h.Write([]byte(tmpl))
+	return h.Sum(nil)
+}
+
+// statusClass - класс http статуса, например 5 для 5xx
+func statusClass(status uint16) uint8 {
+	return uint8(status / 100)
+}
+
+// modelKey - префикс ключа индекса по модели
+func modelKey(mtp ModelType, mid string) []byte {
+	return modelPrefix(uint32(mtp.ID()), []byte(mid))
+}
+
+// modelPrefix - тип и идентификатор модели, сразу за ним в ключе индекса идет время старта записи
+func modelPrefix(mtp uint32, mid []byte) []byte {
+	entp := make([]byte, 4)
+	binary.BigEndian.PutUint32(entp, mtp)
+	return Concat(entp, mid)
+}
+
+/*
+	isModelKey - ключ индекса относится именно к этой модели.
+
+	* После префикса модели в ключе время старта и идентификатор записи, он же значение ключа
+	* Идентификатор модели ничем не отделен от времени, поэтому более длинные идентификаторы отбрасываем по длине
+*/
+func isModelKey(key fdbx.Pair, pref []byte) bool {
+	return len(orm.UnwrapIndexKey(key.Key()).Bytes()) == len(pref)+8+len(key.Value())
 }
@@ -1,43 +1,113 @@
 package journal
 
 import (
-	"encoding/binary"
 	"strings"
 	"time"
 
 	"github.com/shestakovda/fdbx/v2"
 	"github.com/shestakovda/fdbx/v2/orm"
+	"github.com/shestakovda/journal/internal/search"
 	"github.com/shestakovda/journal/models"
 )
 
 func idxJournal(buf []byte) (map[uint16][]fdbx.Key, error) {
 	var mid []byte
+	var code []byte
 
 	stg := new(models.FdbxStage)
 	mod := models.GetRootAsFdbxJournal(buf, 0)
 
-	entp := make([]byte, 4)
 	clen := mod.ChainLength()
-	keys := make([]fdbx.Key, 0, clen)
 	start := fdbx.Time2Byte(time.Unix(0, mod.Start()))
 
+	// Одинаковые модели и ошибки в одной записи индексируем один раз
+	mids := make(map[string]fdbx.Key)
+	codes := make(map[string]fdbx.Key)
+	stats := make(map[uint8]fdbx.Key)
+	tmpls := make(map[string]fdbx.Key)
+
 	for i := 0; i < clen; i++ {
 		if !mod.Chain(stg, i) {
 			return nil, ErrInsert.WithStack()
 		}
 
+		if status := stg.Status(); status > 0 {
+			stats[statusClass(status)] = fdbx.Bytes2Key(Concat([]byte{statusClass(status)}, start))
+		}
+
+		if code = stg.Code(); len(code) > 0 {
+			codes[string(code)] = fdbx.Bytes2Key(Concat(codeKey(string(code)), start))
+		}
+
+		if tmpl := stg.Tmpl(); len(tmpl) > 0 {
+			tmpls[string(tmpl)] = fdbx.Bytes2Key(Concat(templateKey(string(tmpl)), start))
+		}
+
 		if mid = stg.Mid(); len(mid) == 0 {
 			continue
 		}
 
-		binary.BigEndian.PutUint32(entp, uint32(stg.Mtp()))
-		keys = append(keys, fdbx.Bytes2Key(mid).LPart(entp...).RPart(start...))
+		pref := modelPrefix(uint32(stg.Mtp()), mid)
+		mids[string(pref)] = fdbx.Bytes2Key(Concat(pref, start))
+	}
+
+	words := journalTokens(mod)
+	texts := make([]fdbx.Key, len(words))
+
+	for i := range words {
+		texts[i] = fdbx.Bytes2Key(search.Key(words[i], start))
+	}
+
+	dur := Concat([]byte{durationBucket(time.Duration(mod.Total()))}, start)
+	total := totalKey(time.Duration(mod.Total()))
+
+	res := map[uint16][]fdbx.Key{
+		IndexStart:    []fdbx.Key{fdbx.Bytes2Key(start)},
+		IndexDuration: []fdbx.Key{fdbx.Bytes2Key(dur)},
+		IndexTotal:    []fdbx.Key{fdbx.Bytes2Key(total)},
+		IndexText:     texts,
+	}
+
+	if srv := mod.Service(); len(srv) > 0 {
+		res[IndexServiceDuration] = []fdbx.Key{fdbx.Bytes2Key(Concat(serviceKey(string(srv)), dur))}
+		res[IndexServiceTotal] = []fdbx.Key{fdbx.Bytes2Key(Concat(serviceKey(string(srv)), total))}
 	}
 
-	return map[uint16][]fdbx.Key{
-		IndexStart: []fdbx.Key{fdbx.Bytes2Key(start)},
-		IndexModel: keys,
-	}, nil
+	for _, key := range mids {
+		res[IndexModel] = append(res[IndexModel], key)
+	}
+
+	for _, key := range codes {
+		res[IndexCrashCode] = append(res[IndexCrashCode], key)
+	}
+
+	for _, key := range stats {
+		res[IndexStatus] = append(res[IndexStatus], key)
+	}
+
+	for _, key := range tmpls {
+		res[IndexTemplate] = append(res[IndexTemplate], key)
+	}
+
+	return res, nil
+}
+
+// filterByModel - записи, которые ссылаются на модель, по префиксу индекса попадают и модели с более длинным идентификатором
+func filterByModel(mtp ModelType, mid string) orm.Filter {
+	entp := int32(mtp.ID())
+
+	return func(row fdbx.Pair) (bool, error) {
+		stg := new(models.FdbxStage)
+		mod := models.GetRootAsFdbxJournal(row.Value(), 0)
+
+		for i := 0; i < mod.ChainLength(); i++ {
+			if mod.Chain(stg, i) && stg.Mtp() == entp && string(stg.Mid()) == mid {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	}
 }
 
 func filterByService(services []string) orm.Filter {
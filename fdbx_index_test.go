@@ -0,0 +1,61 @@
+package journal
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/shestakovda/fdbx/v2"
+	"github.com/shestakovda/fdbx/v2/orm"
+	"github.com/shestakovda/journal/models"
+	"github.com/shestakovda/typex"
+	"github.com/stretchr/testify/suite"
+)
+
+type FdbxIndexSuite struct {
+	suite.Suite
+}
+
+func (s *FdbxIndexSuite) TestModel() {
+	buf := fdbx.FlatPack(&models.FdbxJournalT{
+		Start: time.Now().UnixNano(),
+		Chain: []*models.FdbxStageT{
+			{Mtp: 42, Mid: "eventID"},
+			{Mtp: 42, Mid: "eventID"},
+			{Mtp: 42, Mid: "eventID2"},
+			{Mtp: 43, Mid: "eventID"},
+		},
+	})
+
+	// Одна и та же модель индексируется один раз
+	if keys, err := idxJournal(buf); s.NoError(err) && s.Len(keys[IndexModel], 3) {
+		pref := modelKey(testModelType(42), "eventID")
+		uid := typex.NewUUID()
+		cnt := 0
+
+		// По префиксу попадают и более длинные идентификаторы, их отбрасываем по длине ключа
+		for _, key := range keys[IndexModel] {
+			if bytes.HasPrefix(key.Bytes(), pref) {
+				cnt++
+				row := fdbx.NewPair(orm.WrapIndexKey(1, IndexModel, key).RPart(uid...), uid)
+				s.Equal(len(key.Bytes()) == len(pref)+8, isModelKey(row, pref))
+			}
+		}
+
+		s.Equal(2, cnt)
+	}
+
+	// Записи без модели отбрасываются
+	row := fdbx.NewPair(fdbx.Bytes2Key(typex.NewUUID()), buf)
+
+	if ok, err := filterByModel(testModelType(42), "eventID2")(row); s.NoError(err) {
+		s.True(ok)
+	}
+
+	if ok, err := filterByModel(testModelType(42), "eventID3")(row); s.NoError(err) {
+		s.False(ok)
+	}
+
+	if ok, err := filterByModel(testModelType(43), "eventID2")(row); s.NoError(err) {
+		s.False(ok)
+	}
+}
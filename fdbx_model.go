@@ -16,22 +16,17 @@ func newFdbxModel(fac *fdbxFactory) *fdbxModel {
 }
 
 func loadFdbxModel(fac *fdbxFactory, uid typex.UUID, buf []byte) *fdbxModel {
-	obj := models.GetRootAsFdbxJournal(buf, 0).UnPack()
+	obj := models.GetRootAsFdbxJournal(buf, 0)
 
-	mod := &fdbxModel{
+	// Цепочку не разбираем, пока она не понадобится
+	return &fdbxModel{
 		uid:   uid,
 		fac:   fac,
-		sid:   obj.Service,
-		start: time.Unix(0, obj.Start).UTC(),
-		total: time.Duration(obj.Total),
-		chain: make([]*fdbxStage, len(obj.Chain)),
-	}
-
-	for i := range obj.Chain {
-		mod.chain[i] = loadFdbxStage(obj.Chain[i])
+		buf:   buf,
+		sid:   string(obj.Service()),
+		start: time.Unix(0, obj.Start()).UTC(),
+		total: time.Duration(obj.Total()),
 	}
-
-	return mod
 }
 
 type fdbxModel struct {
@@ -41,6 +36,7 @@ type fdbxModel struct {
 	total time.Duration
 	chain []*fdbxStage
 
+	buf []byte
 	fac *fdbxFactory
 }
 
@@ -49,13 +45,14 @@ func (m *fdbxModel) Import(e *Entry) (err error) {
 		return ErrValidate.WithReason(err).WithDetail("Некорректный формат идентификатора")
 	}
 
+	m.buf = nil
 	m.sid = e.Service
 	m.total = e.Total
 	m.start = e.Start.UTC()
 	m.chain = make([]*fdbxStage, len(e.Chain))
 
 	for i := range e.Chain {
-		m.chain[i] = newFdbxStage(e.Chain[i])
+		m.chain[i] = newFdbxStage(e.Chain[i], m.fac.opt)
 
 		if e.Chain[i].Fail != nil {
 			if err = m.fac.crf.New().Import(e.Chain[i].Fail); err != nil {
@@ -64,12 +61,24 @@ func (m *fdbxModel) Import(e *Entry) (err error) {
 		}
 	}
 
-	return m.save()
+	if err = m.save(); err != nil {
+		return err
+	}
+
+	if m.fac.opt.rollups {
+		if err = m.rollup(); err != nil {
+			return ErrInsert.WithReason(err)
+		}
+	}
+
+	return nil
 }
 
 func (m *fdbxModel) Export(withCrash bool) (e *Entry, err error) {
 	var crm crash.Model
 
+	m.unpack()
+
 	e = &Entry{
 		ID:      m.uid.Hex(),
 		Service: m.sid,
@@ -93,26 +102,56 @@ func (m *fdbxModel) Export(withCrash bool) (e *Entry, err error) {
 	return e, nil
 }
 
-func (m *fdbxModel) ExportAPI(log Provider) (a *API) {
+func (m *fdbxModel) ExportAPI(log Provider) *API {
+	return m.exportAPI(getAPIOptions(nil))
+}
+
+func (m *fdbxModel) ExportAPIWithOptions(log Provider, args ...APIOption) (a *API, err error) {
+	opts := getAPIOptions(args)
+	a = m.exportAPI(opts)
+
+	if err = exportCrashAPI(m.fac.crf, opts.fails); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (m *fdbxModel) exportAPI(opts *apiOptions) (a *API) {
+	var wait time.Duration
+
+	m.unpack()
+
 	a = &API{
 		ID:     m.uid.Hex(),
 		Start:  m.start,
 		Total:  m.total,
-		Stages: make([]*StageAPI, len(m.chain)),
+		Stages: make([]*StageAPI, 0, len(m.chain)),
 	}
 
 	for i := range m.chain {
-		a.Stages[i] = m.chain[i].ExportAPI()
+		// Время скрытых отметок переходит к следующей, чтобы не терялось
+		if wait += m.chain[i].dur; opts.skip(m.chain[i].verb) {
+			continue
+		}
 
-		if i == 0 {
-			a.Name = a.Stages[i].Name
+		stg := m.chain[i].ExportAPI(opts)
+		stg.Wait = wait
+		wait = 0
+
+		if len(a.Stages) == 0 {
+			a.Name = stg.Name
 		}
+
+		a.Stages = append(a.Stages, stg)
 	}
 
 	return a
 }
 
 func (m *fdbxModel) ExportMonitoring(log Provider) (v *ViewMonitoring) {
+	m.unpack()
+
 	v = &ViewMonitoring{
 		ID:      m.uid.Hex(),
 		Service: m.sid,
@@ -133,6 +172,74 @@ func (m *fdbxModel) ExportMonitoring(log Provider) (v *ViewMonitoring) {
 	return v
 }
 
+func (m *fdbxModel) ExportSummary() *Summary {
+	v := &Summary{
+		ID:      m.uid.Hex(),
+		Service: m.sid,
+		Start:   m.start,
+		Total:   m.total,
+	}
+
+	if m.buf == nil {
+		for i := range m.chain {
+			if i == 0 {
+				v.Name = m.chain[i].msg
+				v.Tmpl = m.chain[i].tmpl
+			}
+
+			if m.chain[i].mtp.ID() == ModelTypeCrash.ID() {
+				v.Fail = true
+			}
+		}
+		return v
+	}
+
+	// Название и шаблон берем из первой отметки, а признак ошибки из типов, не разбирая всю цепочку
+	var stg models.FdbxStage
+
+	obj := models.GetRootAsFdbxJournal(m.buf, 0)
+
+	for i := 0; i < obj.ChainLength(); i++ {
+		if !obj.Chain(&stg, i) {
+			continue
+		}
+
+		if i == 0 {
+			v.Name = string(stg.Msg())
+			v.Tmpl = string(stg.Tmpl())
+		}
+
+		if int(stg.Mtp()) == ModelTypeCrash.ID() {
+			v.Fail = true
+		}
+	}
+
+	return v
+}
+
+// unpack - разбор цепочки отметок при первом обращении к ней
+func (m *fdbxModel) unpack() {
+	if m.buf == nil {
+		return
+	}
+
+	var stg models.FdbxStage
+
+	obj := models.GetRootAsFdbxJournal(m.buf, 0)
+	m.chain = make([]*fdbxStage, obj.ChainLength())
+
+	for i := range m.chain {
+		obj.Chain(&stg, i)
+		m.chain[i] = loadFdbxStage(stg.UnPack())
+	}
+
+	m.buf = nil
+}
+
+func (m *fdbxModel) token() string {
+	return newToken(m.start, m.uid)
+}
+
 func (m *fdbxModel) save() (err error) {
 	obj := &models.FdbxJournalT{
 		Service: m.sid,
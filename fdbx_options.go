@@ -0,0 +1,45 @@
+package journal
+
+// FdbxOption - настройка фабрики и драйвера журнала для fdbx/v2
+type FdbxOption func(*fdbxOptions)
+
+/*
+	FdbxRollups - поддержка сверток агрегатов при сохранении записей.
+
+	* При каждом сохранении атомарно увеличиваются счетчики поминутных, почасовых и посуточных корзин
+	* Factory.Aggregate использует свертки, если параметры запроса совпадают с границами корзин
+	* Устаревшие мелкие корзины удаляются через SweepFdbxRollups
+	* Записи учитываются по шаблону первой отметки, а если его нет, то по ее тексту, обрезанному до 128 байт
+	* Кол-во названий ограничено RollupMaxNames, записи до включения сверток в них не попадают
+*/
+func FdbxRollups() FdbxOption {
+	return func(o *fdbxOptions) {
+		o.rollups = true
+	}
+}
+
+/*
+	FdbxStageArgs - сохранение в отметках отформатированных аргументов шаблона вместе с текстом.
+
+	* Без этой настройки сохраняются только текст и шаблон, чтобы не удваивать объем записей
+*/
+func FdbxStageArgs() FdbxOption {
+	return func(o *fdbxOptions) {
+		o.args = true
+	}
+}
+
+type fdbxOptions struct {
+	args    bool
+	rollups bool
+}
+
+func getFdbxOptions(args []FdbxOption) *fdbxOptions {
+	o := new(fdbxOptions)
+
+	for i := range args {
+		args[i](o)
+	}
+
+	return o
+}
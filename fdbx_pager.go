@@ -0,0 +1,59 @@
+package journal
+
+import (
+	"github.com/shestakovda/errx"
+	"github.com/shestakovda/fdbx/v2/mvcc"
+)
+
+func newFdbxPager(fac *fdbxFactory) fdbxPager {
+	return fdbxPager{
+		fac:  fac,
+		base: fac,
+	}
+}
+
+/*
+	fdbxPager - транзакции чтения курсора fdbx, общие для всех курсоров.
+
+	* fac - фабрика в текущей транзакции, из нее загружаются следующие страницы
+	* base - фабрика, в которой курсор создан, в нее курсор возвращается после release
+
+	* Долгий перебор продолжается в новых транзакциях, чтобы не упираться в ограничение времени FDB
+	* Предыдущие транзакции не завершаются до release, ими пользуются уже выданные записи
+*/
+type fdbxPager struct {
+	fac  *fdbxFactory
+	base *fdbxFactory
+	owns []mvcc.Tx
+}
+
+// renew - следующие страницы загружаются в новой транзакции чтения
+func (p *fdbxPager) renew() (err error) {
+	var tx mvcc.Tx
+
+	if tx, err = mvcc.Begin(p.base.tx.Conn()); err != nil {
+		return errx.ErrInternal.WithReason(err)
+	}
+
+	p.owns = append(p.owns, tx)
+	p.fac = p.base.withTx(tx)
+	return nil
+}
+
+// release - завершение всех начатых транзакций, записи, загруженные в них, больше использовать нельзя
+func (p *fdbxPager) release() (err error) {
+	for i := range p.owns {
+		if exp := p.owns[i].Cancel(); exp != nil && err == nil {
+			err = errx.ErrInternal.WithReason(exp)
+		}
+	}
+
+	p.owns = nil
+	p.fac = p.base
+	return err
+}
+
+// renewed - курсор сейчас читает не в транзакции фабрики
+func (p *fdbxPager) renewed() bool {
+	return len(p.owns) > 0
+}
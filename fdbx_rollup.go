@@ -0,0 +1,298 @@
+package journal
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/shestakovda/errx"
+	"github.com/shestakovda/fdbx/v2"
+	"github.com/shestakovda/fdbx/v2/db"
+	"github.com/shestakovda/fdbx/v2/mvcc"
+	"github.com/shestakovda/journal/internal/ttl"
+)
+
+// Максимальная длина названия записи в ключе свертки, более длинные обрезаются
+const rollupMaxName = 128
+
+// Кол-во значений свертки, загружаемых за одну физическую транзакцию
+const rollupPack = 10000
+
+// Сроки хранения мелких корзин сверток, посуточные хранятся всегда
+var (
+	RollupMinuteTTL = 48 * time.Hour
+	RollupHourTTL   = 90 * 24 * time.Hour
+)
+
+// RollupMaxNames - максимальное кол-во пар сервиса и названия в свертках, остальные учитываются без названия
+var RollupMaxNames = 10000
+
+// rollupLevel - уровень свертки с размером корзины
+type rollupLevel struct {
+	id  byte
+	res time.Duration
+	ttl *time.Duration
+}
+
+// Уровни сверток от самых крупных
+var rollupLevels = []*rollupLevel{
+	{id: 3, res: 24 * time.Hour},
+	{id: 2, res: time.Hour, ttl: &RollupHourTTL},
+	{id: 1, res: time.Minute, ttl: &RollupMinuteTTL},
+}
+
+/*
+	SweepFdbxRollups - периодическое удаление устаревших поминутных и почасовых корзин сверток.
+
+	* dbc - подключение к БД
+	* journalID - номер таблицы журнала, как в NewFdbxFactory
+	* wait - интервал между проходами очистки
+	* fail - обработчик ошибок очистки, может быть пустым, как в SweepFdbxCursors
+
+	* Записи при сохранении сразу учитываются во всех уровнях, поэтому крупные корзины уже готовы
+	* Работает до отмены контекста, ошибки очистки не прерывают цикл
+*/
+func SweepFdbxRollups(ctx context.Context, dbc db.Connection, journalID uint16, wait time.Duration, fail func(error)) {
+	ttl.Loop(ctx, wait, func() error {
+		return sweepFdbxRollups(dbc, journalID, time.Now())
+	}, fail)
+}
+
+// sweepFdbxRollups - удаление корзин, которые старше срока хранения на момент now
+func sweepFdbxRollups(dbc db.Connection, tbid uint16, now time.Time) (err error) {
+	if err = dbc.Write(func(w db.Writer) error {
+		for _, lvl := range rollupLevels {
+			if lvl.ttl != nil {
+				w.Erase(rollupLevelKey(tbid, lvl), rollupTimeKey(tbid, lvl, now.Add(-*lvl.ttl)))
+			}
+		}
+		return nil
+	}); err != nil {
+		return ErrSweep.WithReason(err)
+	}
+
+	return nil
+}
+
+// rollup - увеличение счетчиков всех уровней вместе с фиксацией транзакции сохранения записи
+func (m *fdbxModel) rollup() (err error) {
+	var name string
+	var fresh bool
+
+	sum := m.ExportSummary()
+	bin := sketchBin(sum.Total)
+	tbid := m.fac.tbl.ID()
+
+	if name, fresh, err = rollupName(m.fac.tx, tbid, sum); err != nil {
+		return err
+	}
+
+	// При фиксации только атомарные и слепые записи, поэтому параллельные записи не конфликтуют
+	m.fac.tx.OnCommit(func(w db.Writer) error {
+		if fresh {
+			w.Upsert(fdbx.NewPair(rollupNameKey(tbid, sum.Service, name), []byte{1}))
+			w.Increment(rollupNamesKey(tbid), 1)
+		}
+
+		for _, lvl := range rollupLevels {
+			w.Increment(rollupKey(tbid, lvl, sum, name, bin), 1)
+		}
+		return nil
+	})
+
+	return nil
+}
+
+/*
+	rollupName - название записи в свертке: шаблон первой отметки, а если его нет, то ее текст.
+
+	* Текст с идентификаторами и числами порождал бы новую строку свертки на каждую запись, шаблон - нет
+	* Новые названия учитываются в реестре, когда их больше RollupMaxNames, запись идет без названия
+	* Реестр читается до фиксации, fresh - название нужно добавить в реестр вместе с ней
+	* Параллельные записи с новыми названиями могут превысить предел, но не больше чем на их кол-во
+*/
+func rollupName(tx mvcc.Tx, tbid uint16, sum *Summary) (name string, fresh bool, err error) {
+	if name = sum.Tmpl; name == "" {
+		name = sum.Name
+	}
+
+	name = rollupText(name, rollupMaxName)
+	key := rollupNameKey(tbid, sum.Service, name)
+
+	err = tx.Conn().Read(func(r db.Reader) error {
+		if len(r.Data(key).Value()) > 0 {
+			return nil
+		}
+
+		if val := r.Data(rollupNamesKey(tbid)).Value(); len(val) == 8 && binary.LittleEndian.Uint64(val) >= uint64(RollupMaxNames) {
+			name = ""
+			return nil
+		}
+
+		fresh = true
+		return nil
+	})
+
+	return name, fresh, err
+}
+
+/*
+	rollupFor - уровень свертки, по которому можно построить ряды без потери точности.
+
+	* Начало и конец интервала и шаг должны совпадать с границами корзин, а корзины должны еще храниться
+	* Конец интервала входит в него, поэтому границей корзины должен быть следующий за ним момент
+	* Выбирается самый крупный подходящий уровень, если такого нет, то nil и записи перебираются целиком
+*/
+func (f *fdbxFactory) rollupFor(q *AggQuery) *rollupLevel {
+	// В свертках хранятся шаблоны записей, а не их тексты
+	if !f.opt.rollups || (q.ByName && !q.ByTemplate) {
+		return nil
+	}
+
+	for _, lvl := range rollupLevels {
+		if !rollupAligned(q.From, lvl) || !rollupAligned(q.To.Add(time.Nanosecond), lvl) || q.Step%lvl.res != 0 {
+			continue
+		}
+
+		if lvl.ttl != nil && time.Since(q.From) > *lvl.ttl {
+			continue
+		}
+
+		return lvl
+	}
+
+	return nil
+}
+
+// rollupAligned - момент совпадает с границей корзины уровня
+func rollupAligned(t time.Time, lvl *rollupLevel) bool {
+	return t.Truncate(lvl.res).Equal(t)
+}
+
+// aggregateRollup - загрузка рядов из корзин свертки пачками
+func (f *fdbxFactory) aggregateRollup(ctx context.Context, agg *aggregator, lvl *rollupLevel) (_ []*Series, err error) {
+	var rows []fdbx.Pair
+
+	tbid := f.tbl.ID()
+	skip := false
+	from := rollupTimeKey(tbid, lvl, agg.q.From)
+	last := rollupTimeKey(tbid, lvl, agg.q.To)
+	pref := len(rollupLevelKey(tbid, lvl).Bytes())
+
+	for {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if err = f.tx.Conn().Read(func(r db.Reader) error {
+			rows = r.List(from, last, rollupPack, false, skip).Resolve()
+			return nil
+		}); err != nil {
+			return nil, errx.ErrInternal.WithReason(err).WithDebug(errx.Debug{
+				"От момента": agg.q.From.UTC().Format(time.RFC3339Nano),
+				"До момента": agg.q.To.UTC().Format(time.RFC3339Nano),
+			})
+		}
+
+		for i := range rows {
+			val := rows[i].Value()
+			start, srv, name, fail, bin, ok := parseRollupKey(rows[i].Key().Bytes()[pref:])
+
+			if ok && len(val) == 8 {
+				agg.addBin(srv, name, start, fail, bin, binary.LittleEndian.Uint64(val))
+			}
+		}
+
+		if len(rows) < rollupPack {
+			return agg.series(), nil
+		}
+
+		skip = true
+		from = rows[len(rows)-1].Key()
+	}
+}
+
+// rollupLevelKey - префикс всех корзин уровня
+func rollupLevelKey(tbid uint16, lvl *rollupLevel) fdbx.Key {
+	return mvcc.WrapKey(fdbx.Bytes2Key(Concat(ttlPrefix(tbid, nsRollup).Bytes(), []byte{lvl.id})))
+}
+
+// rollupTimeKey - начало корзин уровня с указанного момента
+func rollupTimeKey(tbid uint16, lvl *rollupLevel, start time.Time) fdbx.Key {
+	return rollupLevelKey(tbid, lvl).RPart(fdbx.Time2Byte(start.Truncate(lvl.res))...)
+}
+
+// rollupKey - счетчик записей одной корзины наброска, в одной корзине времени, сервиса и названия
+func rollupKey(tbid uint16, lvl *rollupLevel, sum *Summary, name string, bin int32) fdbx.Key {
+	var fail byte
+	var tail [4]byte
+
+	if sum.Fail {
+		fail = 1
+	}
+
+	// Порядок номеров корзин сохраняется в порядке байт
+	binary.BigEndian.PutUint32(tail[:], uint32(bin)^0x80000000)
+
+	return rollupTimeKey(tbid, lvl, sum.Start).RPart(Concat(
+		[]byte(rollupText(sum.Service, len(sum.Service))), []byte{0},
+		[]byte(name), []byte{0},
+		[]byte{fail},
+		tail[:],
+	)...)
+}
+
+// rollupNamesKey - кол-во названий в реестре сверток
+func rollupNamesKey(tbid uint16) fdbx.Key {
+	return mvcc.WrapKey(ttlPrefix(tbid, nsRollupName))
+}
+
+// rollupNameKey - название сервиса в реестре сверток
+func rollupNameKey(tbid uint16, srv, name string) fdbx.Key {
+	return rollupNamesKey(tbid).RPart(Concat([]byte(rollupText(srv, len(srv))), []byte{0}, []byte(name))...)
+}
+
+// parseRollupKey - разбор ключа свертки после префикса уровня
+func parseRollupKey(buf []byte) (start time.Time, srv, name string, fail bool, bin int32, ok bool) {
+	if len(buf) < 8+2+5 {
+		return
+	}
+
+	start, _ = fdbx.Byte2Time(buf[:8])
+	buf = buf[8:]
+
+	i := bytes.IndexByte(buf, 0)
+
+	if i < 0 {
+		return
+	}
+
+	srv, buf = string(buf[:i]), buf[i+1:]
+
+	if i = bytes.IndexByte(buf, 0); i < 0 || len(buf) < i+6 {
+		return
+	}
+
+	name, buf = string(buf[:i]), buf[i+1:]
+	fail = buf[0] == 1
+	bin = int32(binary.BigEndian.Uint32(buf[1:5]) ^ 0x80000000)
+	return start, srv, name, fail, bin, true
+}
+
+// rollupText - часть ключа без разделителей и не длиннее size байт
+func rollupText(txt string, size int) string {
+	txt = strings.Replace(txt, "\x00", "", -1)
+
+	if len(txt) <= size {
+		return txt
+	}
+
+	for size > 0 && !utf8.RuneStart(txt[size]) {
+		size--
+	}
+
+	return txt[:size]
+}
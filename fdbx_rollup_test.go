@@ -0,0 +1,78 @@
+package journal
+
+import (
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type FdbxRollupSuite struct {
+	suite.Suite
+}
+
+func (s *FdbxRollupSuite) TestLevel() {
+	fac := &fdbxFactory{opt: &fdbxOptions{rollups: true}}
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+
+	// Целые сутки считаются по посуточной свертке
+	q := &AggQuery{From: day, To: day.Add(24*time.Hour - time.Nanosecond)}
+
+	if lvl := fac.rollupFor(q); s.NotNil(lvl) {
+		s.Equal(24*time.Hour, lvl.res)
+	}
+
+	// Часть суток - по почасовой
+	q.To = day.Add(time.Hour - time.Nanosecond)
+
+	if lvl := fac.rollupFor(q); s.NotNil(lvl) {
+		s.Equal(time.Hour, lvl.res)
+	}
+
+	// Конец интервала не на границе корзины, свертки не подходят
+	q.To = day.Add(time.Hour)
+	s.Nil(fac.rollupFor(q))
+
+	// Названия в свертках не хранятся, только шаблоны
+	q.To = day.Add(time.Hour - time.Nanosecond)
+	q.ByName = true
+	s.Nil(fac.rollupFor(q))
+
+	q.ByTemplate = true
+	s.NotNil(fac.rollupFor(q))
+}
+
+func (s *FdbxRollupSuite) TestKey() {
+	start := time.Date(2020, 5, 17, 13, 42, 17, 123, time.UTC)
+	sum := &Summary{Service: "srv\x00x", Start: start, Fail: true}
+
+	// Запись попадает в корзину каждого уровня по началу своего интервала
+	for _, lvl := range rollupLevels {
+		key := rollupKey(0x4321, lvl, sum, "name", -3).Bytes()
+		pref := len(rollupLevelKey(0x4321, lvl).Bytes())
+
+		if at, srv, name, fail, bin, ok := parseRollupKey(key[pref:]); s.True(ok) {
+			s.True(start.Truncate(lvl.res).Equal(at), lvl.res)
+			s.Equal("srvx", srv)
+			s.Equal("name", name)
+			s.True(fail)
+			s.Equal(int32(-3), bin)
+		}
+	}
+
+	// Порядок корзин наброска сохраняется и для отрицательных номеров
+	lvl := rollupLevels[0]
+	k1 := rollupKey(0x4321, lvl, sum, "name", -1).Bytes()
+	k2 := rollupKey(0x4321, lvl, sum, "name", 1).Bytes()
+	s.True(string(k1) < string(k2))
+
+	_, _, _, _, _, ok := parseRollupKey([]byte("short"))
+	s.False(ok)
+}
+
+func (s *FdbxRollupSuite) TestText() {
+	s.Equal("abc", rollupText("a\x00bc", 10))
+	s.Equal("ab", rollupText("abc", 2))
+
+	// Обрезается по границе символа
+	s.Equal("ж", rollupText("жж", 3))
+}
@@ -0,0 +1,263 @@
+package journal
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	"github.com/shestakovda/errx"
+	"github.com/shestakovda/fdbx/v2"
+	"github.com/shestakovda/fdbx/v2/mvcc"
+	"github.com/shestakovda/fdbx/v2/orm"
+	"github.com/shestakovda/journal/internal/search"
+	"github.com/shestakovda/journal/models"
+	"github.com/shestakovda/typex"
+)
+
+func newFdbxSearchCursor(fac *fdbxFactory, query string, from, last time.Time, services []string) *fdbxSearchCursor {
+	return &fdbxSearchCursor{
+		srvs: services,
+		text: query,
+		scan: search.Scan{
+			Index:  IndexText,
+			Words:  search.Tokenize(query),
+			From:   time.Unix(0, from.UTC().UnixNano()),
+			Last:   time.Unix(0, last.UTC().UnixNano()),
+			Tokens: func(val []byte) []string { return journalTokens(models.GetRootAsFdbxJournal(val, 0)) },
+		},
+		fdbxPager: newFdbxPager(fac),
+	}
+}
+
+// loadFdbxSearchCursor - загрузка курсора поиска, если под этим идентификатором сохранен обычный курсор, то nil
+func loadFdbxSearchCursor(fac *fdbxFactory, qid string) (_ *fdbxSearchCursor, err error) {
+	var row fdbx.Pair
+	var uid typex.UUID
+
+	if uid, err = typex.ParseUUID(qid); err != nil {
+		return nil, ErrValidate.WithReason(err).WithDetail("Некорректный формат идентификатора")
+	}
+
+	if row, err = fac.tx.Select(searchMetaKey(fac.tbl.ID(), uid)); err != nil {
+		if errx.Is(err, mvcc.ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, errx.ErrInternal.WithReason(err).WithDebug(errx.Debug{
+			"Курсор": qid,
+		})
+	}
+
+	if err = fac.checkCursorTTL(qid); err != nil {
+		return nil, err
+	}
+
+	// Время начала и конца интервала, длина позиции, позиция и сам запрос
+	buf := row.Value()
+
+	if len(buf) < 18 || len(buf) < 18+int(binary.BigEndian.Uint16(buf[16:18])) {
+		return nil, errx.ErrInternal.WithDetail("Некорректные параметры поиска").WithDebug(errx.Debug{
+			"Курсор": qid,
+		})
+	}
+
+	from, _ := fdbx.Byte2Time(buf[0:8])
+	last, _ := fdbx.Byte2Time(buf[8:16])
+	size := 18 + int(binary.BigEndian.Uint16(buf[16:18]))
+
+	cur := newFdbxSearchCursor(fac, string(buf[size:]), from, last, nil)
+	cur.qid = qid
+
+	if size > 18 {
+		cur.pos = buf[18:size]
+	}
+
+	return cur, nil
+}
+
+/*
+	fdbxSearchCursor - перебор по индексу слов, сохраняется в БД вместе с запросом и позицией.
+
+	* Фильтры orm не сохраняются вместе с запросом и не видят ключ индекса, поэтому курсор свой
+	* Позиция хранится в памяти, поэтому после долгого перебора ее достаточно сохранить в транзакции фабрики
+	* srvs - сервисы, указанные при создании курсора, как и у обычного курсора в БД не сохраняются
+*/
+type fdbxSearchCursor struct {
+	fdbxPager
+	empty bool
+
+	qid  string
+	pos  []byte
+	text string
+	srvs []string
+	scan search.Scan
+}
+
+func (c *fdbxSearchCursor) ID() string {
+	return c.qid
+}
+
+func (c *fdbxSearchCursor) Empty() bool {
+	return c.empty
+}
+
+// Token - позиция хранится в самом курсоре, а не в маркере
+func (c *fdbxSearchCursor) Token() string {
+	return ""
+}
+
+func (c *fdbxSearchCursor) Close() (err error) {
+	c.empty = true
+
+	if err = c.fdbxPager.release(); err != nil {
+		return err
+	}
+
+	if err = c.fac.dropCursorTTL(c.qid); err != nil {
+		return errx.ErrInternal.WithReason(err).WithDebug(errx.Debug{
+			"Курсор": c.qid,
+		})
+	}
+
+	return nil
+}
+
+func (c *fdbxSearchCursor) Each(ctx context.Context, size uint, fn func(Model) error) error {
+	return eachModel(ctx, c, size, fn)
+}
+
+func (c *fdbxSearchCursor) Stream(ctx context.Context, size uint) (<-chan Model, <-chan error) {
+	return streamModels(ctx, c, size)
+}
+
+func (c *fdbxSearchCursor) NextExport(size uint, mode ExportMode, services ...string) ([]*Entry, error) {
+	return nextExport(c, c.fac.crf, size, mode, services)
+}
+
+func (c *fdbxSearchCursor) NextSummary(size uint, services ...string) ([]*Summary, error) {
+	return nextSummary(c, size, services)
+}
+
+func (c *fdbxSearchCursor) NextPage(size uint, services ...string) (res []Model, err error) {
+	var mods []*fdbxModel
+
+	if c.empty || size == 0 {
+		return nil, nil
+	}
+
+	if mods, err = c.next(int(size), services); err != nil {
+		return nil, errx.ErrInternal.WithReason(err).WithDebug(errx.Debug{
+			"Курсор":  c.qid,
+			"Сервисы": services,
+		})
+	}
+
+	if err = c.save(); err != nil {
+		return nil, errx.ErrInternal.WithReason(err).WithDebug(errx.Debug{
+			"Курсор": c.qid,
+		})
+	}
+
+	return fdbxModels(mods), nil
+}
+
+// release - позиция, сохраненная в новых транзакциях, сохраняется и в транзакции фабрики
+func (c *fdbxSearchCursor) release() (err error) {
+	if !c.renewed() {
+		return nil
+	}
+
+	if err = c.fdbxPager.release(); err != nil {
+		return err
+	}
+
+	if err = c.save(); err != nil {
+		return errx.ErrInternal.WithReason(err).WithDebug(errx.Debug{
+			"Курсор": c.qid,
+		})
+	}
+
+	return nil
+}
+
+// next - следующие записи по индексу слов, позиция и признак конца перебора остаются в курсоре
+func (c *fdbxSearchCursor) next(size int, services []string) (mods []*fdbxModel, err error) {
+	var pos []byte
+	var done bool
+	var rows []fdbx.Pair
+
+	scan := c.scan
+	srvs := make([]orm.Filter, 0, 2)
+
+	if len(c.srvs) > 0 {
+		srvs = append(srvs, filterByService(c.srvs))
+	}
+
+	if len(services) > 0 {
+		srvs = append(srvs, filterByService(services))
+	}
+
+	scan.Filter = func(row fdbx.Pair) (ok bool, err error) {
+		for i := range srvs {
+			if ok, err = srvs[i](row); err != nil || !ok {
+				return ok, err
+			}
+		}
+
+		return true, nil
+	}
+
+	if rows, pos, done, err = scan.Next(c.fac.tx, c.fac.tbl, c.pos, size); err != nil {
+		return nil, err
+	}
+
+	c.pos = pos
+	c.empty = done
+	mods = make([]*fdbxModel, len(rows))
+
+	for i := range rows {
+		mods[i] = loadFdbxModel(c.fac, typex.UUID(rows[i].Key().Bytes()), rows[i].Value())
+	}
+
+	return mods, nil
+}
+
+// save - сохранение запроса и текущей позиции, как при загрузке страницы обычного курсора
+func (c *fdbxSearchCursor) save() (err error) {
+	var uid typex.UUID
+
+	if c.qid == "" {
+		uid = typex.NewUUID()
+		c.qid = uid.Hex()
+
+		if err = c.fac.saveCursorTTL(c.qid); err != nil {
+			return err
+		}
+	} else if uid, err = typex.ParseUUID(c.qid); err != nil {
+		return err
+	}
+
+	size := make([]byte, 2)
+	binary.BigEndian.PutUint16(size, uint16(len(c.pos)))
+	buf := Concat(fdbx.Time2Byte(c.scan.From), fdbx.Time2Byte(c.scan.Last), size, c.pos, []byte(c.text))
+
+	return c.fac.tx.Upsert([]fdbx.Pair{fdbx.NewPair(searchMetaKey(c.fac.tbl.ID(), uid), buf)})
+}
+
+// journalTokens - слова текстов всех этапов записи
+func journalTokens(mod *models.FdbxJournal) []string {
+	stg := new(models.FdbxStage)
+	txt := make([]byte, 0, 256)
+
+	for i := 0; i < mod.ChainLength(); i++ {
+		if mod.Chain(stg, i) {
+			txt = append(append(txt, ' '), stg.Msg()...)
+		}
+	}
+
+	return search.Tokenize(string(txt))
+}
+
+func searchMetaKey(tbid uint16, uid typex.UUID) fdbx.Key {
+	return fdbx.Bytes2Key(Concat(ttlPrefix(tbid, nsCursorSearch).Bytes(), uid))
+}
@@ -0,0 +1,371 @@
+package journal
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"math/bits"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shestakovda/errx"
+	"github.com/shestakovda/fdbx/v2"
+	"github.com/shestakovda/fdbx/v2/orm"
+	"github.com/shestakovda/journal/models"
+	"github.com/shestakovda/typex"
+)
+
+const verTokenDur byte = 2
+
+// Корзина с самыми долгими записями, длительность не может быть больше MaxInt64
+const maxDurationBucket uint8 = 63
+
+func newFdbxSlowCursor(
+	fac *fdbxFactory,
+	ord SlowOrder,
+	min time.Duration,
+	from time.Time,
+	last time.Time,
+	token string,
+	services []string,
+) (cur *fdbxSlowCursor, err error) {
+	if err = checkSlowOrder(ord); err != nil {
+		return nil, err
+	}
+
+	cur = &fdbxSlowCursor{
+		ord:       ord,
+		min:       min,
+		tok:       token,
+		idx:       IndexDuration,
+		from:      fdbx.Time2Byte(from),
+		last:      fdbx.Time2Byte(last),
+		srvs:      services,
+		fdbxPager: newFdbxPager(fac),
+	}
+
+	if ord == SlowByDuration {
+		cur.idx = IndexTotal
+	}
+
+	// Если сервис один, то у него есть свой индекс
+	if len(services) == 1 {
+		if cur.idx = IndexServiceDuration; ord == SlowByDuration {
+			cur.idx = IndexServiceTotal
+		}
+
+		cur.pref = serviceKey(services[0])
+		cur.srvs = nil
+	}
+
+	if err = cur.seek(token); err != nil {
+		return nil, err
+	}
+
+	return cur, nil
+}
+
+// fdbxSlowCursor - перебор по индексу длительности без сохранения курсора, позиция передается маркером
+type fdbxSlowCursor struct {
+	fdbxPager
+	empty bool
+
+	ord  SlowOrder
+	min  time.Duration
+	idx  uint16
+	tok  string
+	pref []byte
+	from []byte
+	last []byte
+	srvs []string
+
+	// Позиция в переборе по времени
+	start time.Time
+
+	// Позиция в переборе по длительности
+	total time.Duration
+
+	// Последняя выданная запись, в обоих порядках
+	uid typex.UUID
+}
+
+func (c *fdbxSlowCursor) ID() string {
+	return ""
+}
+
+func (c *fdbxSlowCursor) Empty() bool {
+	return c.empty
+}
+
+func (c *fdbxSlowCursor) Token() string {
+	return c.tok
+}
+
+// Close - курсор нигде не сохранен, поэтому просто прекращаем перебор и завершаем его транзакции
+func (c *fdbxSlowCursor) Close() error {
+	c.empty = true
+	return c.release()
+}
+
+func (c *fdbxSlowCursor) Each(ctx context.Context, size uint, fn func(Model) error) error {
+	return eachModel(ctx, c, size, fn)
+}
+
+func (c *fdbxSlowCursor) Stream(ctx context.Context, size uint) (<-chan Model, <-chan error) {
+	return streamModels(ctx, c, size)
+}
+
+func (c *fdbxSlowCursor) NextExport(size uint, mode ExportMode, services ...string) ([]*Entry, error) {
+	return nextExport(c, c.fac.crf, size, mode, services)
+}
+
+func (c *fdbxSlowCursor) NextSummary(size uint, services ...string) ([]*Summary, error) {
+	return nextSummary(c, size, services)
+}
+
+func (c *fdbxSlowCursor) NextPage(size uint, services ...string) (res []Model, err error) {
+	if c.empty || size == 0 {
+		return nil, nil
+	}
+
+	if c.ord == SlowByDuration {
+		res, err = c.nextByDuration(int(size), services)
+	} else {
+		res, err = c.nextByTime(int(size), services)
+	}
+
+	if err != nil {
+		return nil, errx.ErrInternal.WithReason(err).WithDebug(errx.Debug{
+			"Маркер":  c.tok,
+			"Сервисы": services,
+		})
+	}
+
+	if len(res) < int(size) {
+		c.empty = true
+	}
+
+	return res, nil
+}
+
+// nextByTime - в каждой корзине записи уже отсортированы по времени, остается только их слить
+func (c *fdbxSlowCursor) nextByTime(size int, services []string) (res []Model, err error) {
+	var rows []fdbx.Pair
+
+	mods := make([]*fdbxModel, 0, size)
+
+	for n := int(durationBucket(c.min)); n <= int(maxDurationBucket); n++ {
+		b := uint8(n)
+		last := Concat(c.pref, []byte{b}, c.last)
+
+		if c.uid != nil {
+			if last = keyBefore(Concat(c.pref, []byte{b}, fdbx.Time2Byte(c.start), c.uid)); last == nil {
+				continue
+			}
+		}
+
+		que := c.query(b, last, services).Reverse().Limit(size)
+
+		if rows, err = que.All(); err != nil {
+			return nil, err
+		}
+
+		for i := range rows {
+			mods = append(mods, loadFdbxModel(c.fac, typex.UUID(rows[i].Key().Bytes()), rows[i].Value()))
+		}
+	}
+
+	sort.Slice(mods, func(i, j int) bool {
+		if !mods[i].start.Equal(mods[j].start) {
+			return mods[i].start.After(mods[j].start)
+		}
+		return bytes.Compare(mods[i].uid, mods[j].uid) > 0
+	})
+
+	if len(mods) > size {
+		mods = mods[:size]
+	}
+
+	if len(mods) > 0 {
+		if err = c.seek(mods[len(mods)-1].token()); err != nil {
+			return nil, err
+		}
+	}
+
+	return fdbxModels(mods), nil
+}
+
+// nextByDuration - ключи индекса упорядочены по длительности, поэтому страница загружается одним интервалом
+func (c *fdbxSlowCursor) nextByDuration(size int, services []string) (res []Model, err error) {
+	var rows []fdbx.Pair
+
+	from, last := durationRange(c.pref, c.min, c.total, c.uid)
+
+	if last == nil {
+		return nil, nil
+	}
+
+	que := c.fac.tbl.Select(c.fac.tx).ByIndexRange(c.idx, fdbx.Bytes2Key(from), fdbx.Bytes2Key(last))
+	que = c.filter(que.Where(filterByStart(c.from, c.last)), services)
+
+	if rows, err = que.Reverse().Limit(size).All(); err != nil {
+		return nil, err
+	}
+
+	mods := make([]*fdbxModel, len(rows))
+
+	for i := range rows {
+		mods[i] = loadFdbxModel(c.fac, typex.UUID(rows[i].Key().Bytes()), rows[i].Value())
+	}
+
+	if len(mods) > 0 {
+		last := mods[len(mods)-1]
+		c.total = last.total
+		c.uid = last.uid
+		c.tok = newDurToken(last.total, last.uid)
+	}
+
+	return fdbxModels(mods), nil
+}
+
+/*
+	durationRange - интервал индекса длительности от min до позиции перебора.
+
+	* В ключе индекса после длительности идет идентификатор записи, поэтому позиция задается ими обоими
+	* Если позиции нет, интервал до самых долгих записей, если перед позицией ничего нет, last пустой
+*/
+func durationRange(pref []byte, min, total time.Duration, uid typex.UUID) (from, last []byte) {
+	from = Concat(pref, totalKey(min))
+
+	if uid == nil {
+		return from, Concat(pref, []byte{0xFF})
+	}
+
+	return from, keyBefore(Concat(pref, totalKey(total), uid))
+}
+
+// query - выборка записей одной корзины в интервале времени
+func (c *fdbxSlowCursor) query(b uint8, last []byte, services []string) orm.Query {
+	que := c.fac.tbl.Select(c.fac.tx).ByIndexRange(
+		c.idx,
+		fdbx.Bytes2Key(Concat(c.pref, []byte{b}, c.from)),
+		fdbx.Bytes2Key(last),
+	)
+
+	// В корзину попадают и записи чуть быстрее нужного
+	return c.filter(que.Where(filterByDuration(c.min)), services)
+}
+
+// filter - отбор записей по сервисам курсора и страницы
+func (c *fdbxSlowCursor) filter(que orm.Query, services []string) orm.Query {
+	if len(c.srvs) > 0 {
+		que = que.Where(filterByService(c.srvs))
+	}
+
+	if len(services) > 0 {
+		que = que.Where(filterByService(services))
+	}
+
+	return que
+}
+
+// seek - разбор маркера позиции в зависимости от порядка
+func (c *fdbxSlowCursor) seek(token string) (err error) {
+	if c.ord == SlowByDuration {
+		var total time.Duration
+
+		if total, c.uid, err = parseDurToken(token); err != nil || c.uid == nil {
+			return err
+		}
+
+		c.tok = token
+		c.total = total
+		return nil
+	}
+
+	if c.start, c.uid, err = parseToken(token); err != nil || c.uid == nil {
+		return err
+	}
+
+	c.tok = token
+	return nil
+}
+
+// durationBucket - логарифмическая корзина длительности, номер старшего бита
+func durationBucket(d time.Duration) uint8 {
+	if d <= 0 {
+		return 0
+	}
+
+	return uint8(bits.Len64(uint64(d)))
+}
+
+// serviceKey - префикс ключа индекса по сервису, с разделителем, чтобы сервисы не были префиксами друг друга
+func serviceKey(srv string) []byte {
+	return Concat([]byte(strings.ToLower(srv)), []byte{0})
+}
+
+// totalKey - длительность в ключе индекса, порядок ключей совпадает с порядком длительностей
+func totalKey(total time.Duration) []byte {
+	buf := make([]byte, 8)
+
+	if total > 0 {
+		binary.BigEndian.PutUint64(buf, uint64(total))
+	}
+
+	return buf
+}
+
+// newDurToken - маркер позиции в переборе по длительности
+func newDurToken(total time.Duration, uid typex.UUID) string {
+	buf := make([]byte, 9+len(uid))
+	buf[0] = verTokenDur
+	binary.BigEndian.PutUint64(buf[1:9], uint64(total))
+	copy(buf[9:], uid)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// parseDurToken - разбор маркера позиции в переборе по длительности
+func parseDurToken(token string) (total time.Duration, uid typex.UUID, err error) {
+	var buf []byte
+
+	if token == "" {
+		return 0, nil, nil
+	}
+
+	if buf, err = base64.RawURLEncoding.DecodeString(token); err != nil {
+		return 0, nil, ErrValidate.WithReason(err).WithDetail("Некорректный маркер позиции")
+	}
+
+	if len(buf) < 10 || buf[0] != verTokenDur {
+		return 0, nil, ErrValidate.WithDetail("Некорректный маркер позиции").WithDebug(errx.Debug{
+			"Маркер": token,
+		})
+	}
+
+	return time.Duration(binary.BigEndian.Uint64(buf[1:9])), typex.UUID(buf[9:]), nil
+}
+
+// filterByStart - записи, начатые в интервале, границы как в Time2Byte
+func filterByStart(from, last []byte) orm.Filter {
+	return func(row fdbx.Pair) (bool, error) {
+		start := fdbx.Time2Byte(time.Unix(0, models.GetRootAsFdbxJournal(row.Value(), 0).Start()))
+		return bytes.Compare(start, from) >= 0 && bytes.Compare(start, last) <= 0, nil
+	}
+}
+
+func filterByDuration(min time.Duration) orm.Filter {
+	return func(row fdbx.Pair) (bool, error) {
+		return time.Duration(models.GetRootAsFdbxJournal(row.Value(), 0).Total()) >= min, nil
+	}
+}
+
+func fdbxModels(mods []*fdbxModel) []Model {
+	res := make([]Model, len(mods))
+	for i := range mods {
+		res[i] = mods[i]
+	}
+	return res
+}
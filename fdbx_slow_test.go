@@ -0,0 +1,79 @@
+package journal
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/shestakovda/errx"
+	"github.com/shestakovda/fdbx/v2"
+	"github.com/shestakovda/journal/models"
+	"github.com/shestakovda/typex"
+	"github.com/stretchr/testify/suite"
+)
+
+type FdbxSlowSuite struct {
+	suite.Suite
+}
+
+func (s *FdbxSlowSuite) TestOrder() {
+	s.NoError(checkSlowOrder(SlowByTime))
+	s.NoError(checkSlowOrder(SlowByDuration))
+
+	if err := checkSlowOrder(SlowOrder(42)); s.Error(err) {
+		s.True(errx.Is(err, ErrValidate))
+	}
+
+	// Курсор с неизвестным порядком не создается
+	if _, err := newFdbxSlowCursor(nil, SlowOrder(42), 0, time.Now(), time.Now(), "", nil); s.Error(err) {
+		s.True(errx.Is(err, ErrValidate))
+	}
+}
+
+func (s *FdbxSlowSuite) TestTotalKey() {
+	s.Equal(make([]byte, 8), totalKey(-time.Second))
+	s.Equal(-1, bytes.Compare(totalKey(0), totalKey(time.Nanosecond)))
+	s.Equal(-1, bytes.Compare(totalKey(255*time.Nanosecond), totalKey(256*time.Nanosecond)))
+	s.Equal(-1, bytes.Compare(totalKey(time.Second), totalKey(time.Hour)))
+}
+
+func (s *FdbxSlowSuite) TestRange() {
+	uid := typex.NewUUID()
+	pref := serviceKey("srv")
+
+	// Без позиции - от min до самых долгих
+	from, last := durationRange(pref, time.Second, 0, nil)
+	s.Equal(Concat(pref, totalKey(time.Second)), from)
+	s.Equal(Concat(pref, []byte{0xFF}), last)
+
+	// С позицией - все, что перед ней, сама позиция в интервал не попадает
+	key := Concat(pref, totalKey(time.Minute), uid)
+	from, last = durationRange(pref, time.Second, time.Minute, uid)
+
+	s.Equal(Concat(pref, totalKey(time.Second)), from)
+	s.Equal(-1, bytes.Compare(last, key))
+	s.Equal(1, bytes.Compare(last, Concat(pref, totalKey(time.Minute-1), uid)))
+}
+
+func (s *FdbxSlowSuite) TestIndex() {
+	now := time.Now()
+	buf := fdbx.FlatPack(&models.FdbxJournalT{
+		Start:   now.UnixNano(),
+		Total:   int64(time.Minute),
+		Service: "Srv",
+	})
+
+	if keys, err := idxJournal(buf); s.NoError(err) {
+		s.Equal([]fdbx.Key{fdbx.Bytes2Key(totalKey(time.Minute))}, keys[IndexTotal])
+		s.Equal([]fdbx.Key{fdbx.Bytes2Key(Concat(serviceKey("srv"), totalKey(time.Minute)))}, keys[IndexServiceTotal])
+	}
+
+	// Интервал дат включает границы
+	row := fdbx.NewPair(fdbx.Bytes2Key(typex.NewUUID()), buf)
+	ok, err := filterByStart(fdbx.Time2Byte(now), fdbx.Time2Byte(now))(row)
+	s.NoError(err)
+	s.True(ok)
+
+	ok, err = filterByStart(fdbx.Time2Byte(now.Add(time.Nanosecond)), fdbx.Time2Byte(now.Add(time.Hour)))(row)
+	s.NoError(err)
+	s.False(ok)
+}
@@ -6,45 +6,78 @@ import (
 	"github.com/shestakovda/journal/models"
 )
 
-func newFdbxStage(s *Stage) *fdbxStage {
-	return &fdbxStage{
-		dur: s.Wait,
-		msg: s.Text,
-		mid: s.EnID,
-		mtp: getType(s.Type),
+func newFdbxStage(s *Stage, opt *fdbxOptions) *fdbxStage {
+	stg := &fdbxStage{
+		dur:  s.Wait,
+		msg:  s.Text,
+		mid:  s.EnID,
+		tmpl: s.Tmpl,
+		verb: s.Verb,
+		mtp:  getType(s.Type),
 	}
+
+	if opt.args {
+		stg.args = s.Args
+	}
+
+	// Код и статус ошибки храним в отметке, чтобы искать по ним без загрузки отчетов
+	if s.Fail != nil {
+		stg.code = s.Fail.Code
+		stg.status = s.Fail.Status
+	}
+
+	return stg
 }
 
 func loadFdbxStage(s *models.FdbxStageT) *fdbxStage {
-	return &fdbxStage{
-		msg: s.Msg,
-		mid: s.Mid,
-		mtp: getType(int(s.Mtp)),
-		dur: time.Duration(s.Dur),
+	stg := &fdbxStage{
+		msg:    s.Msg,
+		mid:    s.Mid,
+		mtp:    getType(int(s.Mtp)),
+		dur:    time.Duration(s.Dur),
+		verb:   int(s.Verb),
+		code:   s.Code,
+		tmpl:   s.Tmpl,
+		status: s.Status,
 	}
+
+	// Пустой список аргументов не отличаем от отсутствующего
+	if len(s.Args) > 0 {
+		stg.args = s.Args
+	}
+
+	return stg
 }
 
 type fdbxStage struct {
-	dur time.Duration
-	mtp ModelType
-	mid string
-	msg string
+	dur    time.Duration
+	mtp    ModelType
+	mid    string
+	msg    string
+	verb   int
+	code   string
+	tmpl   string
+	args   []string
+	status uint16
 }
 
 func (s *fdbxStage) Export() *Stage {
 	return &Stage{
 		Wait: s.dur,
 		Text: s.msg,
+		Tmpl: s.tmpl,
+		Args: s.args,
 		EnID: s.mid,
+		Verb: s.verb,
 		Type: s.mtp.ID(),
 	}
 }
 
-func (s *fdbxStage) ExportAPI() *StageAPI {
-	return &StageAPI{
+func (s *fdbxStage) ExportAPI(opts *apiOptions) *StageAPI {
+	return opts.stage(&StageAPI{
 		Name: s.msg,
 		Wait: s.dur,
-	}
+	}, s.mtp, s.mid)
 }
 
 func (s *fdbxStage) ExportMonitoring() *StageMonitoring {
@@ -64,9 +97,14 @@ func (s *fdbxStage) ExportMonitoring() *StageMonitoring {
 
 func (s *fdbxStage) dump() *models.FdbxStageT {
 	return &models.FdbxStageT{
-		Msg: s.msg,
-		Mid: s.mid,
-		Dur: int64(s.dur),
-		Mtp: int32(s.mtp.ID()),
+		Msg:    s.msg,
+		Mid:    s.mid,
+		Dur:    int64(s.dur),
+		Mtp:    int32(s.mtp.ID()),
+		Verb:   int32(s.verb),
+		Code:   s.code,
+		Tmpl:   s.tmpl,
+		Args:   s.args,
+		Status: s.status,
 	}
 }
@@ -0,0 +1,102 @@
+package journal
+
+import (
+	"context"
+	"time"
+
+	"github.com/shestakovda/errx"
+	"github.com/shestakovda/fdbx/v2"
+	"github.com/shestakovda/fdbx/v2/db"
+	"github.com/shestakovda/fdbx/v2/orm"
+	"github.com/shestakovda/journal/internal/ttl"
+	"github.com/shestakovda/typex"
+)
+
+/*
+	SweepFdbxCursors - периодическое удаление истекших курсоров журнала, сохраненных через fdbx/v2.
+
+	* dbc - подключение к БД
+	* journalID - номер таблицы журнала, как в NewFdbxFactory
+	* wait - интервал между проходами очистки
+	* fail - обработчик ошибок очистки, может быть пустым, как в crash.SweepFdbxCursors
+
+	* Работает до отмены контекста, ошибки очистки не прерывают цикл
+*/
+func SweepFdbxCursors(ctx context.Context, dbc db.Connection, journalID uint16, wait time.Duration, fail func(error)) {
+	ttl.Loop(ctx, wait, func() error {
+		return sweepFdbxCursors(dbc, journalID, time.Now())
+	}, fail)
+}
+
+// sweepFdbxCursors - один проход очистки истекших курсоров на момент now
+func sweepFdbxCursors(dbc db.Connection, tbid uint16, now time.Time) error {
+	if err := cursorSpace(tbid).Sweep(dbc, now, CursorGrace); err != nil {
+		return ErrSweep.WithReason(err)
+	}
+
+	return nil
+}
+
+// saveCursorTTL - сохранение срока действия нового курсора
+func (f *fdbxFactory) saveCursorTTL(qid string) (err error) {
+	var uid typex.UUID
+
+	if uid, err = typex.ParseUUID(qid); err != nil {
+		return err
+	}
+
+	return cursorSpace(f.tbl.ID()).Save(f.tx, uid, time.Now().Add(CursorTTL))
+}
+
+// checkCursorTTL - проверка срока действия курсора, у курсоров без срока он не ограничен
+func (f *fdbxFactory) checkCursorTTL(qid string) (err error) {
+	var exp time.Time
+	var uid typex.UUID
+
+	if uid, err = typex.ParseUUID(qid); err != nil {
+		return ErrValidate.WithReason(err).WithDetail("Некорректный формат идентификатора")
+	}
+
+	if exp, err = cursorSpace(f.tbl.ID()).Expire(f.tx, uid); err != nil {
+		return errx.ErrInternal.WithReason(err).WithDebug(errx.Debug{
+			"Курсор": qid,
+		})
+	}
+
+	if !exp.IsZero() && exp.Before(time.Now()) {
+		return ErrExpired.WithDebug(errx.Debug{
+			"Курсор":  qid,
+			"Истек в": exp.UTC().Format(time.RFC3339Nano),
+		})
+	}
+
+	return nil
+}
+
+// dropCursorTTL - удаление сведений о сроке действия и параметров поиска закрытого курсора
+func (f *fdbxFactory) dropCursorTTL(qid string) (err error) {
+	var uid typex.UUID
+
+	if uid, err = typex.ParseUUID(qid); err != nil {
+		return err
+	}
+
+	return cursorSpace(f.tbl.ID()).Drop(f.tx, uid)
+}
+
+// cursorSpace - области учета срока жизни курсоров таблицы журнала, по истечении удаляются запрос и параметры поиска
+func cursorSpace(tbid uint16) *ttl.Space {
+	return &ttl.Space{
+		Table:  tbid,
+		Meta:   nsCursorMeta,
+		Queue:  nsCursorTTL,
+		Vacuum: []byte{nsCursorSearch},
+		Data: func(uid typex.UUID) []fdbx.Key {
+			return []fdbx.Key{orm.WrapQueryKey(tbid, fdbx.Bytes2Key(uid)), searchMetaKey(tbid, uid)}
+		},
+	}
+}
+
+func ttlPrefix(tbid uint16, ns byte) fdbx.Key {
+	return ttl.Prefix(tbid, ns)
+}
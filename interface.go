@@ -1,6 +1,8 @@
 package journal
 
 import (
+	"context"
+	"fmt"
 	"time"
 
 	"github.com/shestakovda/fdbx/v2/db"
@@ -8,26 +10,79 @@ import (
 	"github.com/shestakovda/journal/crash"
 )
 
-// Типы для моделей по-умолчанию
+// Типы для моделей по-умолчанию, новые типы журнала занимают номера от ModelTypeReserved
 const (
-	ModelTypeUnknown journalModels = 0
-	ModelTypeCrash   journalModels = 1
+	ModelTypeUnknown     journalModels = 0
+	ModelTypeCrash       journalModels = 1
+	ModelTypeRemoteCrash journalModels = 0xFF01
 )
 
+// ModelTypeReserved - начало номеров типов, занятых журналом, в приложении их регистрировать нельзя
+const ModelTypeReserved = 0xFF00
+
 // Константы индексов
 const (
-	IndexStart uint16 = 0x0001
-	IndexModel uint16 = 0x0002
+	IndexStart     uint16 = 0x0001
+	IndexModel     uint16 = 0x0002
+	IndexCrashCode uint16 = 0x0003
+	IndexStatus    uint16 = 0x0004
+
+	IndexDuration        uint16 = 0x0005
+	IndexServiceDuration uint16 = 0x0006
+
+	IndexText     uint16 = 0x0007
+	IndexTemplate uint16 = 0x0008
+
+	IndexTotal        uint16 = 0x0009
+	IndexServiceTotal uint16 = 0x000A
+)
+
+// Order - порядок перебора записей в курсоре
+type Order uint8
+
+// Допустимые порядки перебора
+const (
+	OrderDesc Order = 0 // Сначала новые записи, по-умолчанию
+	OrderAsc  Order = 1 // Сначала старые записи
 )
 
+// SlowOrder - порядок перебора медленных записей
+type SlowOrder uint8
+
+// Допустимые порядки перебора медленных записей
+const (
+	SlowByTime     SlowOrder = 0 // Сначала новые записи
+	SlowByDuration SlowOrder = 1 // Сначала самые долгие записи
+)
+
+// Сроки жизни сохраненных курсоров
+var (
+	CursorTTL   = 24 * time.Hour // Через сколько после создания курсор считается истекшим
+	CursorGrace = 24 * time.Hour // Сколько после удаления истекшего курсора о нем помнить
+)
+
+// ExportMode - какие сведения об ошибках добавлять при пакетной выгрузке записей
+type ExportMode uint8
+
+// Допустимые режимы пакетной выгрузки
+const (
+	ExportNoCrash    ExportMode = 0 // Без отчетов об ошибках, как Export(false)
+	ExportCrash      ExportMode = 1 // С полными отчетами об ошибках, как Export(true)
+	ExportCrashBrief ExportMode = 2 // Только код, статус и заголовок ошибки
+)
+
+// StreamRenew - через сколько при долгом переборе курсора по маркеру начинать новую транзакцию чтения.
+// Новая транзакция не видит незавершенных изменений той, в которой создана фабрика
+var StreamRenew = 4 * time.Second
+
 // NewFdbxFactory - конструктор фабрики для загрузки через fdbx/v2
-func NewFdbxFactory(tx mvcc.Tx, journalID, crashID uint16) Factory {
-	return newFdbxFactory(tx, journalID, crashID)
+func NewFdbxFactory(tx mvcc.Tx, journalID, crashID uint16, opts ...FdbxOption) Factory {
+	return newFdbxFactory(tx, journalID, crashID, opts...)
 }
 
 // NewFdbxDriver - конструктор драйвера для сохранения через fdbx/v2
-func NewFdbxDriver(dbc db.Connection, journalID, crashID uint16) Driver {
-	return newFdbxDriver(dbc, journalID, crashID)
+func NewFdbxDriver(dbc db.Connection, journalID, crashID uint16, opts ...FdbxOption) Driver {
+	return newFdbxDriver(dbc, journalID, crashID, opts...)
 }
 
 // ModelType - абстрактный тип модели для логирования
@@ -36,8 +91,38 @@ type ModelType interface {
 	String() string
 }
 
-// RegisterType - регистрация типа для корректной загрузки данных из БД
-func RegisterType(types ...ModelType) { regType(types...) }
+/*
+	RegisterType - регистрация типа для корректной загрузки данных из БД.
+
+	* Номера от ModelTypeReserved и выше заняты типами журнала, например ModelTypeRemoteCrash
+	* Тип с таким номером заменил бы тип журнала, поэтому паника, и ни один тип из списка не регистрируется
+*/
+func RegisterType(types ...ModelType) {
+	for _, mtp := range types {
+		if mtp != nil && mtp.ID() >= ModelTypeReserved {
+			panic(fmt.Sprintf("Номер типа %d занят журналом", mtp.ID()))
+		}
+	}
+
+	regType(types...)
+}
+
+/*
+	Verbose - уровни подробности записей, провайдер из NewProvider их поддерживает.
+
+	* В Provider не входит, чтобы не ломать сторонние реализации провайдера
+*/
+type Verbose interface {
+	/*
+		V - уровень подробности следующей записи.
+
+		* lvl - уровень подробности, чем больше, тем менее важна запись
+		* Если lvl больше максимального для провайдера, возвращает false и запись делать не нужно
+
+		* Уровень записи учитывается в APIMaxVerbose
+	*/
+	V(lvl int) bool
+}
 
 // Provider сборки и сохранения журнала
 type Provider interface {
@@ -48,6 +133,8 @@ type Provider interface {
 		* args - аргументы форматной строки, могут отсутствовать, если текст не требует форматирования
 
 		* Вызов функции создает новую отметку времени в цепочке
+		* С настройкой ProviderTemplates шаблон сохраняется в отметке отдельно от текста
+		* Аргументы форматируются сразу, их изменение после вызова на отметку не влияет
 	*/
 	Print(txt string, args ...interface{})
 
@@ -60,6 +147,8 @@ type Provider interface {
 		* args - аргументы форматной строки, могут отсутствовать, если текст не требует форматирования
 
 		* Вызов функции создает новую отметку времени в цепочке
+		* С настройкой ProviderTemplates шаблон сохраняется в отметке отдельно от текста
+		* Аргументы форматируются сразу, их изменение после вызова на отметку не влияет
 		* Если указан пустой идентификатор, то функция аналогична вызову Print
 	*/
 	Model(mtp ModelType, mid string, txt string, args ...interface{})
@@ -72,6 +161,7 @@ type Provider interface {
 		* Если err = nil, то возвращается тоже nil
 		* Ошибка логируется как модель с идентификатором типа core.ModelTypeCrash
 		* В текстовый комментарий к модели идет содержимое err.Error()
+		* Если в цепочке есть crash.Problem, ее идентификатор логируется как модель типа ModelTypeRemoteCrash
 	*/
 	Crash(err error) *crash.Report
 
@@ -113,11 +203,43 @@ type Factory interface {
 	*/
 	ByID(id string) (Model, error)
 
+	/*
+		ByIDs - получение записей журнала по списку идентификаторов.
+
+		* ids не должны быть пустыми и валидируются как core.UUID, иначе ErrValidate
+
+		* Найденные записи возвращаются в порядке ids, ненайденные идентификаторы - отдельным списком
+		* Если что-то пошло не так, ErrSelect
+	*/
+	ByIDs(ids ...string) (res []Model, miss []string, err error)
+
 	/*
 		ByModel - получение всех записей журнала по конкретной модели
+
+		Deprecated: для часто используемых моделей загружает сразу тысячи записей,
+		вместо него нужно использовать ByModelDate или SeekModelDate с постраничной загрузкой
 	*/
 	ByModel(mtp ModelType, mid string) ([]Model, error)
 
+	/*
+		ByModelCount - кол-во записей журнала по конкретной модели
+
+		* В реализации на fdbx v2 считаются только ключи индекса, сами записи не загружаются
+		* В NewFactoryFDB записи загружаются, чтобы отбросить модели, идентификатор которых длиннее
+		* Запись, которая ссылается на модель несколько раз, считается один раз
+	*/
+	ByModelCount(mtp ModelType, mid string) (uint64, error)
+
+	/*
+		Export - пакетная выгрузка основного представления записей.
+
+		* mode - какие сведения об ошибках добавлять в отметки
+		* Отчеты об ошибках загружаются одним пакетом для всех записей сразу
+
+		* Если отчет не найден или что-то пошло не так, ErrSelect
+	*/
+	Export(mode ExportMode, mods ...Model) ([]*Entry, error)
+
 	/*
 		Cursor - загрузка существующего курсора
 	*/
@@ -125,6 +247,10 @@ type Factory interface {
 
 	/*
 		ByDate - формирование курсора перебора по дате
+
+		* services - если указаны, выдаются только записи этих сервисов
+		* Сервисы в БД вместе с курсором не сохраняются, после загрузки по идентификатору их передают в NextPage
+		* Сервисы, переданные в NextPage, проверяются вместе с указанными при создании
 	*/
 	ByDate(from, to time.Time, page uint, services ...string) (_ Cursor, err error)
 
@@ -132,6 +258,113 @@ type Factory interface {
 		ByModelDate - формирование курсора перебора по модели и дате
 	*/
 	ByModelDate(mtp ModelType, mid string, from, to time.Time, page uint, services ...string) (_ Cursor, err error)
+
+	/*
+		ByDateOrder - формирование курсора перебора по дате в указанном порядке
+
+		* Курсор сохраняется в БД, как и при вызове ByDate
+		* Если порядок не OrderDesc и не OrderAsc, ErrValidate, как и во всех методах с порядком перебора
+	*/
+	ByDateOrder(ord Order, from, to time.Time, page uint, services ...string) (_ Cursor, err error)
+
+	/*
+		ByModelDateOrder - формирование курсора перебора по модели и дате в указанном порядке
+
+		* Курсор сохраняется в БД, как и при вызове ByModelDate
+	*/
+	ByModelDateOrder(
+		ord Order, mtp ModelType, mid string, from, to time.Time, page uint, services ...string,
+	) (_ Cursor, err error)
+
+	/*
+		ByCrashCode - формирование курсора перебора записей с ошибкой по ее коду и дате
+
+		* Курсор сохраняется в БД, как и при вызове ByDate
+		* Если реализация не поддерживает такой индекс, ErrNotSupported
+	*/
+	ByCrashCode(ord Order, code string, from, to time.Time, page uint, services ...string) (_ Cursor, err error)
+
+	/*
+		ByStatus - формирование курсора перебора записей с ошибкой по классу http статуса и дате
+
+		* class - класс статуса, например 4 для ошибок 4xx или 5 для 5xx
+		* Курсор сохраняется в БД, как и при вызове ByDate
+		* Если реализация не поддерживает такой индекс, ErrNotSupported
+	*/
+	ByStatus(ord Order, class uint8, from, to time.Time, page uint, services ...string) (_ Cursor, err error)
+
+	/*
+		SeekSlow - перебор записей не быстрее min в интервале дат, без сохранения курсора в БД.
+
+		* ord - по времени или по длительности, в обоих случаях сначала новые или самые долгие
+		* token - маркер позиции, полученный из Cursor.Token. Если пустой, перебор с начала
+		* services - если указан один сервис, используется отдельный индекс по нему
+
+		* При переборе по длительности страница загружается одним интервалом индекса длительности
+		* Если порядок или маркер некорректный, ErrValidate
+		* Если реализация не поддерживает такой индекс, ErrNotSupported
+	*/
+	SeekSlow(ord SlowOrder, min time.Duration, from, to time.Time, token string, services ...string) (_ Cursor, err error)
+
+	/*
+		Search - формирование курсора полнотекстового поиска по текстам этапов в интервале дат.
+
+		* query - слова через пробел, каждое должно быть началом какого-то слова в этапах записи
+		* Регистр не важен, поддерживаются любые алфавиты, слова из одного символа не учитываются
+		* Курсор сохраняется в БД вместе с запросом и позицией, срок действия как при вызове ByDate
+		* services - как при вызове ByDate, вместе с курсором не сохраняются
+
+		* Записи упорядочены по словам индекса, а не по времени
+		* Если в запросе нет ни одного слова, ErrValidate
+		* Если реализация не поддерживает такой индекс, ErrNotSupported
+	*/
+	Search(query string, from, to time.Time, page uint, services ...string) (_ Cursor, err error)
+
+	/*
+		ByTemplate - формирование курсора перебора записей, в которых есть отметка по шаблону, и дате
+
+		* tmpl - форматная строка, как она была передана в Provider.Print или Provider.Model
+		* Шаблоны сохраняются только с настройкой ProviderTemplates и только у отметок с аргументами, остальные ищутся через Search
+		* Курсор сохраняется в БД, как и при вызове ByDate
+		* Если реализация не поддерживает такой индекс, ErrNotSupported
+	*/
+	ByTemplate(ord Order, tmpl string, from, to time.Time, page uint, services ...string) (_ Cursor, err error)
+
+	/*
+		Aggregate - временные ряды с кол-вом записей, ошибок и процентилями длительности.
+
+		* ctx - контекст перебора, при отмене возвращается его ошибка
+		* q - интервал, шаг и сервисы, по которым строятся ряды
+
+		* Процентили рассчитываются по Sketch, с точностью SketchAccuracy
+		* В каждом ряду есть все точки интервала, в том числе пустые
+		* Если включены свертки и интервал совпадает с их корзинами, конец интервала округляется до корзины
+		* Если параметры некорректные или точек слишком много, ErrValidate
+	*/
+	Aggregate(ctx context.Context, q *AggQuery) ([]*Series, error)
+
+	/*
+		SeekDate - перебор по дате без сохранения курсора в БД.
+
+		* token - маркер позиции, полученный из Cursor.Token. Если пустой, перебор с начала интервала
+		* Параметры запроса должны совпадать с теми, при которых был получен маркер
+		* services - как при вызове ByDate
+
+		* Если маркер некорректный, ErrValidate
+	*/
+	SeekDate(ord Order, from, to time.Time, token string, services ...string) (_ Cursor, err error)
+
+	/*
+		SeekModelDate - перебор по модели и дате без сохранения курсора в БД.
+
+		* token - маркер позиции, полученный из Cursor.Token. Если пустой, перебор с начала интервала
+		* Параметры запроса должны совпадать с теми, при которых был получен маркер
+
+		* Если маркер некорректный, ErrValidate
+	*/
+	SeekModelDate(
+		ord Order, mtp ModelType, mid string, from, to time.Time, token string, services ...string,
+	) (_ Cursor, err error)
 }
 
 // Model - запись журнала в БД
@@ -148,22 +381,73 @@ type Model interface {
 
 	/*
 		ExportAPI - представление для выдачи журнала в файлах и пакетах
+
+		* Отчеты об ошибках не загружаются, для них есть ExportAPIWithOptions
 	*/
 	ExportAPI(log Provider) *API
 
+	/*
+		ExportAPIWithOptions - представление для выдачи журнала внешним клиентам
+
+		* opts - настройки выдачи, краткие отчеты об ошибках добавляются только с APIWithCrash
+	*/
+	ExportAPIWithOptions(log Provider, opts ...APIOption) (*API, error)
+
 	/*
 		ExportMonitoring - представление для выдачи журнала в мониторинге
 	*/
 	ExportMonitoring(log Provider) *ViewMonitoring
+
+	/*
+		ExportSummary - краткое представление для списков, цепочка отметок при этом не загружается
+	*/
+	ExportSummary() *Summary
 }
 
 // Cursor - модель для крупных выборок с постраничкой
 type Cursor interface {
+	// Идентификатор сохраненного курсора, пустой для перебора без сохранения
 	ID() string
 	Empty() bool
 
+	// Маркер позиции после последней загруженной записи, для перебора без сохранения
+	Token() string
+
 	// Подгрузка следующей страницы (но, возможно, с изменением размера)
 	NextPage(size uint, services ...string) ([]Model, error)
+
+	// Подгрузка следующей страницы в основном представлении, отчеты об ошибках загружаются одним пакетом
+	NextExport(size uint, mode ExportMode, services ...string) ([]*Entry, error)
+
+	// Подгрузка следующей страницы в кратком представлении, полные записи можно получить через ByIDs
+	NextSummary(size uint, services ...string) ([]*Summary, error)
+
+	// Удаление сохраненного курсора, после этого он недоступен по идентификатору
+	Close() error
+
+	/*
+		Each - перебор всех оставшихся записей курсора.
+
+		* ctx - контекст перебора, при отмене возвращается его ошибка
+		* size - размер загружаемой страницы
+		* fn - обработчик записи, при ошибке перебор прекращается и она возвращается как есть
+
+		* Долгий перебор продолжается в новых транзакциях, они завершаются перед выходом из Each,
+		* поэтому использовать записи после обработчика нельзя
+	*/
+	Each(ctx context.Context, size uint, fn func(Model) error) error
+
+	/*
+		Stream - перебор всех оставшихся записей курсора в фоне.
+
+		* ctx - контекст перебора, при отмене фоновая загрузка прекращается
+		* size - размер загружаемой страницы, заранее загружается не больше одной страницы
+
+		* Канал записей закрывается по окончании перебора, после этого в канале ошибок будет не больше одной ошибки
+		* Пока идет перебор, транзакцию фабрики нельзя использовать в других местах
+		* Долгий перебор продолжается в новых транзакциях, они завершаются в Close, после обработки всех записей
+	*/
+	Stream(ctx context.Context, size uint) (<-chan Model, <-chan error)
 }
 
 // Logger - обертка для записи журнала в консольку
@@ -1,8 +1,10 @@
 package journal_test
 
 import (
+	"context"
 	"errors"
 	"net/http"
+	"sort"
 	"testing"
 	"time"
 
@@ -24,6 +26,30 @@ func TestProvider(t *testing.T) {
 	suite.Run(t, new(journal.ProviderSuite))
 }
 
+func TestSketch(t *testing.T) {
+	suite.Run(t, new(journal.SketchSuite))
+}
+
+func TestBreakdown(t *testing.T) {
+	suite.Run(t, new(journal.BreakdownSuite))
+}
+
+func TestStream(t *testing.T) {
+	suite.Run(t, new(journal.StreamSuite))
+}
+
+func TestFdbxIndex(t *testing.T) {
+	suite.Run(t, new(journal.FdbxIndexSuite))
+}
+
+func TestFdbxRollup(t *testing.T) {
+	suite.Run(t, new(journal.FdbxRollupSuite))
+}
+
+func TestFdbxSlow(t *testing.T) {
+	suite.Run(t, new(journal.FdbxSlowSuite))
+}
+
 func TestInterface(t *testing.T) {
 	suite.Run(t, new(InterfaceSuite))
 }
@@ -65,6 +91,26 @@ func (s *InterfaceSuite) TestWorkflowFDB() {
 		return nil
 	}))
 
+	// Перебор без сохранения курсора, в обоих направлениях
+	s.Require().NoError(fdb.Tx(func(db fdbv1.DB) error {
+		s.checkSeek(journal.NewFactoryFDB(fdb, db))
+		s.checkStream(journal.NewFactoryFDB(fdb, db))
+
+		// В этой версии нет индексов по ошибкам
+		if _, err := journal.NewFactoryFDB(fdb, db).ByStatus(journal.OrderAsc, 4, time.Now(), time.Now(), 10); s.Error(err) {
+			s.True(errx.Is(err, journal.ErrNotSupported))
+		}
+
+		if _, err := journal.NewFactoryFDB(fdb, db).Search("ololo", time.Now(), time.Now(), 10); s.Error(err) {
+			s.True(errx.Is(err, journal.ErrNotSupported))
+		}
+
+		if _, err := journal.NewFactoryFDB(fdb, db).ByTemplate(journal.OrderAsc, "ololo %s %d", time.Now(), time.Now(), 10); s.Error(err) {
+			s.True(errx.Is(err, journal.ErrNotSupported))
+		}
+		return nil
+	}))
+
 	// В след. раз загружаем этот курсор и смотрим, чот там есть
 	s.Require().NoError(fdb.Tx(func(db fdbv1.DB) (exp error) {
 		s.checkCursor(journal.NewFactoryFDB(fdb, db), cid)
@@ -90,10 +136,840 @@ func (s *InterfaceSuite) TestWorkflowFdbx() {
 	// Где-то в другом месте его можно получить по айдишке
 	// В след. раз загружаем этот курсор и смотрим, чот там есть
 	s.checkCursor(fac, s.checkSaved(fac, log, rep))
+
+	// Перебор без сохранения курсора, в обоих направлениях
+	s.checkSeek(fac)
+
+	// Перебор всех записей целиком
+	s.checkStream(fac)
+
+	// Долгий перебор в новых транзакциях
+	s.checkRenew(fac)
+
+	// Поиск по коду и статусу ошибки
+	s.checkCrashIndex(fac, rep)
+
+	// Поиск медленных записей
+	s.checkSlow(fac)
+
+	// Полнотекстовый поиск по этапам
+	s.checkSearch(fac)
+
+	// Агрегаты по сервисам и названиям
+	s.checkAggregate(fac)
+
+	// Распределение времени по отметкам
+	if cur, exp := fac.SeekDate(journal.OrderAsc, time.Now().Add(-time.Hour), time.Now(), ""); s.NoError(exp) {
+		if brk, exp := journal.StageBreakdown(context.Background(), cur, 2); s.NoError(exp) && s.Len(brk.Groups, 3) {
+			s.Equal(uint64(1), brk.Groups[0].Count)
+			s.NotEmpty(brk.Groups[0].Stages)
+		}
+	}
+
+	// Полнотекстовый поиск по заголовкам и текстам отчетов об ошибках
+	s.checkCrashSearch(crash.NewFdbxFactory(tx, 0x4321), rep)
+
+	// Постраничный перебор отчетов об ошибках
+	s.checkCrashCursor(crash.NewFdbxFactory(tx, 0x4321), rep)
+
+	// Сводка по одинаковым отчетам
+	s.checkIssues(dbc, rep)
+
+	// Закрытие и истечение срока сохраненных курсоров
+	s.checkLifetime(fac)
+
+	// Агрегаты по сверткам, которые ведутся при сохранении
+	s.checkRollup(dbc, tx)
+}
+
+func (s *InterfaceSuite) TestTemplateFdbx() {
+	dbc, err := db.ConnectV610(0x10)
+	s.Require().NoError(err)
+	s.Require().NoError(dbc.Clear())
+
+	tx, err := mvcc.Begin(dbc)
+	s.Require().NoError(err)
+	defer tx.Cancel()
+
+	drv := journal.NewFdbxDriver(dbc, 0x1234, 0x4321, journal.FdbxStageArgs())
+	fac := journal.NewFdbxFactory(tx, 0x1234, 0x4321)
+
+	s.saveEntries(drv, journal.ProviderTemplates())
+
+	// Поиск по шаблону отметки
+	s.checkTemplate(fac)
+
+	from := time.Now().Add(-time.Hour)
+	to := time.Now().Add(time.Hour)
+
+	// Названия записей разные, но шаблон у всех один
+	if cur, exp := fac.SeekDate(journal.OrderAsc, from, to, ""); s.NoError(exp) {
+		if brk, exp := journal.StageBreakdown(context.Background(), cur, 2); s.NoError(exp) && s.Len(brk.Groups, 1) {
+			s.Equal("ololo %s %d", brk.Groups[0].Name)
+			s.Equal(uint64(3), brk.Groups[0].Count)
+			s.NotEmpty(brk.Groups[0].Stages)
+		}
+	}
+
+	// По шаблону похожие записи попадают в один ряд агрегатов
+	q := &journal.AggQuery{From: from, To: to, ByName: true, ByTemplate: true}
+
+	if list, exp := fac.Aggregate(context.Background(), q); s.NoError(exp) && s.Len(list, 1) {
+		s.Equal("ololo %s %d", list[0].Name)
+		s.Equal(uint64(3), list[0].Points[0].Count)
+	}
+}
+
+func (s *InterfaceSuite) checkCrashSearch(crf crash.Factory, rep *crash.Report) {
+	from := time.Now().Add(-time.Hour)
+	to := time.Now()
+
+	if cur, exp := crf.Search("ДОСТУП some", from, to, 10); s.NoError(exp) {
+		if list, exp := cur.NextPage(0); s.NoError(exp) && s.Len(list, 1) {
+			s.Equal(rep.ID, list[0].Export().ID)
+		}
+
+		// Позиция сохраняется вместе с курсором
+		if cur, exp = crf.Cursor(cur.ID()); s.NoError(exp) {
+			if list, exp := cur.NextPage(0); s.NoError(exp) {
+				s.Empty(list)
+			}
+
+			s.NoError(cur.Close())
+		}
+
+		if _, exp = crf.Cursor(cur.ID()); s.Error(exp) {
+			s.True(errx.Is(exp, crash.ErrNotFound))
+		}
+	}
+
+	if _, exp := crf.Search(" - ", from, to, 10); s.Error(exp) {
+		s.True(errx.Is(exp, crash.ErrQueryValidate))
+	}
+}
+
+func (s *InterfaceSuite) checkStream(fac journal.Factory) {
+	var exp error
+	var cur journal.Cursor
+
+	ctx := context.Background()
+	from := time.Now().Add(-time.Hour)
+	to := time.Now().Add(time.Hour)
+	all := []*journal.Entry{s.entry, s.entry2, s.entry3}
+
+	// Все записи по порядку, страницы меньше общего кол-ва
+	if cur, exp = fac.ByDateOrder(journal.OrderAsc, from, to, 10); s.NoError(exp) {
+		ids := make([]string, 0, len(all))
+
+		s.NoError(cur.Each(ctx, 2, func(mod journal.Model) error {
+			row, err := mod.Export(false)
+			if err == nil {
+				ids = append(ids, row.ID)
+			}
+			return err
+		}))
+
+		s.Equal([]string{s.entry.ID, s.entry2.ID, s.entry3.ID}, ids)
+		s.True(cur.Empty())
+	}
+
+	// Ошибка обработчика прекращает перебор и возвращается как есть
+	if cur, exp = fac.SeekDate(journal.OrderDesc, from, to, ""); s.NoError(exp) {
+		cnt := 0
+		stop := errx.New("stop")
+
+		exp = cur.Each(ctx, 1, func(journal.Model) error {
+			cnt++
+			return stop
+		})
+
+		s.Equal(stop, exp)
+		s.Equal(1, cnt)
+	}
+
+	// Фоновая загрузка в обратном порядке
+	if cur, exp = fac.SeekDate(journal.OrderDesc, from, to, ""); s.NoError(exp) {
+		mods, errs := cur.Stream(ctx, 2)
+
+		for i := range all {
+			if mod, ok := <-mods; s.True(ok) {
+				if row, err := mod.Export(true); s.NoError(err) {
+					s.Equal(all[len(all)-1-i], row)
+				}
+			}
+		}
+
+		_, ok := <-mods
+		s.False(ok)
+		s.NoError(<-errs)
+	}
+
+	// Отмена контекста останавливает фоновую загрузку
+	if cur, exp = fac.SeekDate(journal.OrderAsc, from, to, ""); s.NoError(exp) {
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		mods, errs := cur.Stream(cctx, 1)
+		for range mods {
+		}
+
+		s.True(errors.Is(<-errs, context.Canceled))
+	}
+}
+
+func (s *InterfaceSuite) checkRenew(fac journal.Factory) {
+	var exp error
+	var cur journal.Cursor
+
+	ctx := context.Background()
+	from := time.Now().Add(-time.Hour)
+	to := time.Now().Add(time.Hour)
+	all := []*journal.Entry{s.entry, s.entry2, s.entry3}
+
+	// Новая транзакция перед каждой страницей
+	defer func(renew time.Duration) { journal.StreamRenew = renew }(journal.StreamRenew)
+	journal.StreamRenew = 0
+
+	// Сохраненный курсор: позиция после перебора возвращается в транзакцию фабрики
+	if cur, exp = fac.ByDateOrder(journal.OrderAsc, from, to, 10); s.NoError(exp) {
+		rows := make([]*journal.Entry, 0, len(all))
+
+		s.NoError(cur.Each(ctx, 1, func(mod journal.Model) error {
+			row, err := mod.Export(true)
+			if err == nil {
+				rows = append(rows, row)
+			}
+			return err
+		}))
+
+		s.Equal(all, rows)
+
+		if cur, exp = fac.Cursor(cur.ID()); s.NoError(exp) {
+			if mods, exp := cur.NextPage(10); s.NoError(exp) {
+				s.Empty(mods)
+			}
+		}
+	}
+
+	// Записи предыдущих страниц можно использовать, пока курсор не закрыт
+	if cur, exp = fac.SeekDate(journal.OrderAsc, from, to, ""); s.NoError(exp) {
+		mods, errs := cur.Stream(ctx, 1)
+		list := make([]journal.Model, 0, len(all))
+
+		for mod := range mods {
+			list = append(list, mod)
+		}
+
+		s.NoError(<-errs)
+
+		if s.Len(list, len(all)) {
+			for i := range list {
+				if row, err := list[i].Export(true); s.NoError(err) {
+					s.Equal(all[i], row)
+				}
+			}
+		}
+
+		s.NoError(cur.Close())
+	}
+
+	// Медленные записи перебираются в новых транзакциях так же
+	if cur, exp = fac.SeekSlow(journal.SlowByDuration, 0, from, to, ""); s.NoError(exp) {
+		ids := make(map[string]bool, len(all))
+
+		s.NoError(cur.Each(ctx, 1, func(mod journal.Model) error {
+			ids[mod.ExportSummary().ID] = true
+			return nil
+		}))
+
+		s.Len(ids, len(all))
+	}
+
+	// После Each курсор снова читает в транзакции фабрики
+	if cur, exp = fac.SeekDate(journal.OrderAsc, from, to, ""); s.NoError(exp) {
+		cnt := 0
+		stop := errx.New("stop")
+
+		s.Equal(stop, cur.Each(ctx, 1, func(journal.Model) error {
+			if cnt++; cnt == 2 {
+				return stop
+			}
+			return nil
+		}))
+
+		if mods, exp := cur.NextPage(10); s.NoError(exp) && s.Len(mods, 1) {
+			if row, err := mods[0].Export(true); s.NoError(err) {
+				s.Equal(s.entry3, row)
+			}
+		}
+	}
+}
+
+func (s *InterfaceSuite) checkCrashIndex(fac journal.Factory, rep *crash.Report) {
+	var exp error
+	var cur journal.Cursor
+
+	from := time.Now().Add(-time.Hour)
+	to := time.Now().Add(time.Hour)
+
+	if cur, exp = fac.ByCrashCode(journal.OrderDesc, rep.Code, from, to, 10); s.NoError(exp) {
+		if mods, exp := cur.NextPage(10); s.NoError(exp) && s.Len(mods, 1) {
+			if row, err := mods[0].Export(true); s.NoError(err) {
+				s.Equal(s.entry, row)
+			}
+		}
+	}
+
+	// Код не должен совпадать по префиксу
+	if cur, exp = fac.ByCrashCode(journal.OrderDesc, rep.Code[:len(rep.Code)-1], from, to, 10); s.NoError(exp) {
+		if mods, exp := cur.NextPage(10); s.NoError(exp) {
+			s.Empty(mods)
+		}
+	}
+
+	if cur, exp = fac.ByStatus(journal.OrderAsc, 4, from, to, 10); s.NoError(exp) {
+		if mods, exp := cur.NextPage(10); s.NoError(exp) && s.Len(mods, 1) {
+			if row, err := mods[0].Export(false); s.NoError(err) {
+				s.Equal(s.entry.ID, row.ID)
+			}
+		}
+	}
+
+	if cur, exp = fac.ByStatus(journal.OrderAsc, 5, from, to, 10); s.NoError(exp) {
+		if mods, exp := cur.NextPage(10); s.NoError(exp) {
+			s.Empty(mods)
+		}
+	}
+
+	if cur, exp = fac.ByStatus(journal.OrderAsc, 4, from, to, 10, "unknown"); s.NoError(exp) {
+		if mods, exp := cur.NextPage(10); s.NoError(exp) {
+			s.Empty(mods)
+		}
+	}
+
+	if _, exp = fac.ByCrashCode(journal.Order(7), rep.Code, from, to, 10); s.Error(exp) {
+		s.True(errx.Is(exp, journal.ErrValidate))
+	}
 }
 
-func (s *InterfaceSuite) saveEntries(drv journal.Driver) (journal.Provider, *crash.Report) {
-	log := journal.NewProvider(1, s.crp, drv, nil, "")
+func (s *InterfaceSuite) checkSlow(fac journal.Factory) {
+	var tok string
+	var exp error
+	var cur journal.Cursor
+
+	from := time.Now().Add(-time.Hour)
+	to := time.Now().Add(time.Hour)
+
+	// По времени, сначала новые
+	if cur, exp = fac.SeekSlow(journal.SlowByTime, 0, from, to, ""); s.NoError(exp) {
+		if sums, exp := cur.NextSummary(10); s.NoError(exp) && s.Len(sums, 3) {
+			s.Equal(s.entry3.ID, sums[0].ID)
+			s.Equal(s.entry2.ID, sums[1].ID)
+			s.Equal(s.entry.ID, sums[2].ID)
+		}
+	}
+
+	// По длительности, постранично через маркер
+	all := []*journal.Entry{s.entry, s.entry2, s.entry3}
+	sort.Slice(all, func(i, j int) bool { return all[i].Total > all[j].Total })
+
+	for i := range all {
+		if cur, exp = fac.SeekSlow(journal.SlowByDuration, 0, from, to, tok); s.NoError(exp) {
+			if sums, exp := cur.NextSummary(1); s.NoError(exp) && s.Len(sums, 1, i) {
+				s.Equal(all[i].ID, sums[0].ID)
+			}
+			tok = cur.Token()
+		}
+	}
+
+	if cur, exp = fac.SeekSlow(journal.SlowByDuration, 0, from, to, tok); s.NoError(exp) {
+		if sums, exp := cur.NextSummary(1); s.NoError(exp) {
+			s.Empty(sums)
+		}
+		s.True(cur.Empty())
+	}
+
+	// Порог отсекает все записи
+	if cur, exp = fac.SeekSlow(journal.SlowByTime, all[0].Total+1, from, to, ""); s.NoError(exp) {
+		if sums, exp := cur.NextSummary(10); s.NoError(exp) {
+			s.Empty(sums)
+		}
+	}
+
+	// Только самая долгая запись
+	if cur, exp = fac.SeekSlow(journal.SlowByDuration, all[0].Total, from, to, ""); s.NoError(exp) {
+		if sums, exp := cur.NextSummary(10); s.NoError(exp) && s.Len(sums, 1) {
+			s.Equal(all[0].ID, sums[0].ID)
+		}
+	}
+
+	// По отдельному сервису
+	if cur, exp = fac.SeekSlow(journal.SlowByTime, 0, from, to, "", "unknown"); s.NoError(exp) {
+		if sums, exp := cur.NextSummary(10); s.NoError(exp) {
+			s.Empty(sums)
+		}
+	}
+
+	// Неизвестный порядок
+	if _, exp = fac.SeekSlow(journal.SlowOrder(7), 0, from, to, ""); s.Error(exp) {
+		s.True(errx.Is(exp, journal.ErrValidate))
+	}
+}
+
+func (s *InterfaceSuite) checkSearch(fac journal.Factory) {
+	var exp error
+	var cur journal.Cursor
+
+	from := time.Now().Add(-time.Hour)
+	to := time.Now().Add(time.Hour)
+
+	ids := func(mods []journal.Model) []string {
+		res := make([]string, 0, len(mods))
+		for i := range mods {
+			if row, err := mods[i].Export(false); s.NoError(err) {
+				res = append(res, row.ID)
+			}
+		}
+		sort.Strings(res)
+		return res
+	}
+
+	all := []string{s.entry.ID, s.entry2.ID, s.entry3.ID}
+	sort.Strings(all)
+
+	// Все слова запроса должны найтись в одной записи
+	if cur, exp = fac.Search("OLOLO test2", from, to, 10); s.NoError(exp) {
+		if mods, exp := cur.NextPage(10); s.NoError(exp) {
+			s.Equal([]string{s.entry2.ID}, ids(mods))
+		}
+	}
+
+	// Поиск по началу слова, запись с несколькими подходящими словами выдается один раз
+	if cur, exp = fac.Search("olo", from, to, 10); s.NoError(exp) {
+		if mods, exp := cur.NextPage(10); s.NoError(exp) {
+			s.Equal(all, ids(mods))
+		}
+	}
+
+	// Постраничный перебор загруженного курсора сохраняет фильтр запроса
+	if cur, exp = fac.Search("some comment", from, to, 2); s.NoError(exp) {
+		if mods, exp := cur.NextPage(2); s.NoError(exp) {
+			s.Len(mods, 2)
+		}
+
+		if cur, exp = fac.Cursor(cur.ID()); s.NoError(exp) {
+			if mods, exp := cur.NextPage(2); s.NoError(exp) {
+				s.Len(mods, 1)
+			}
+		}
+	}
+
+	// Закрытый курсор поиска недоступен по идентификатору
+	if cur, exp = fac.Search("some comment", from, to, 2); s.NoError(exp) {
+		s.NoError(cur.Close())
+
+		if _, exp = fac.Cursor(cur.ID()); s.Error(exp) {
+			s.True(errx.Is(exp, errx.ErrNotFound))
+		}
+	}
+
+	// Фильтр по сервисам курсора
+	if cur, exp = fac.Search("olo", from, to, 10, "unknown"); s.NoError(exp) {
+		if mods, exp := cur.NextPage(10); s.NoError(exp) {
+			s.Empty(mods)
+		}
+	}
+
+	// Кириллица из заголовка ошибки, регистр не важен
+	if cur, exp = fac.Search("доступ ЗАПРЕЩ", from, to, 10); s.NoError(exp) {
+		if mods, exp := cur.NextPage(10); s.NoError(exp) {
+			s.Equal([]string{s.entry.ID}, ids(mods))
+		}
+	}
+
+	// Не пройдет по дате
+	if cur, exp = fac.Search("ololo", to, to, 10); s.NoError(exp) {
+		if mods, exp := cur.NextPage(10); s.NoError(exp) {
+			s.Empty(mods)
+		}
+	}
+
+	if _, exp = fac.Search(" - ", from, to, 10); s.Error(exp) {
+		s.True(errx.Is(exp, journal.ErrValidate))
+	}
+}
+
+func (s *InterfaceSuite) checkTemplate(fac journal.Factory) {
+	from := time.Now().Add(-time.Hour)
+	to := time.Now().Add(time.Hour)
+
+	// Аргументы сохраняются отдельно от шаблона
+	s.Equal("some %s", s.entry.Chain[2].Tmpl)
+	s.Equal([]string{"comment1"}, s.entry.Chain[2].Args)
+	s.Equal("[ 403 ] Доступ запрещен", s.entry.Chain[3].Text)
+	s.Empty(s.entry.Chain[3].Tmpl)
+
+	if cur, exp := fac.ByTemplate(journal.OrderAsc, "some %s", from, to, 10); s.NoError(exp) {
+		if mods, exp := cur.NextPage(10); s.NoError(exp) && s.Len(mods, 3) {
+			if row, err := mods[0].Export(true); s.NoError(err) {
+				s.Equal(s.entry, row)
+			}
+		}
+	}
+
+	// Подробная отметка есть только во второй записи
+	if cur, exp := fac.ByTemplate(journal.OrderDesc, "verbose %s", from, to, 10); s.NoError(exp) {
+		if mods, exp := cur.NextPage(10); s.NoError(exp) && s.Len(mods, 1) {
+			s.Equal(s.entry2.ID, mods[0].ExportSummary().ID)
+		}
+	}
+
+	// Хэш шаблона не должен совпадать с похожими шаблонами
+	if cur, exp := fac.ByTemplate(journal.OrderAsc, "some %d", from, to, 10); s.NoError(exp) {
+		if mods, exp := cur.NextPage(10); s.NoError(exp) {
+			s.Empty(mods)
+		}
+	}
+
+	if mod, exp := fac.ByID(s.entry.ID); s.NoError(exp) {
+		s.Equal("ololo %s %d", mod.ExportSummary().Tmpl)
+	}
+}
+
+func (s *InterfaceSuite) checkCrashCursor(fac crash.Factory, rep *crash.Report) {
+	q := &crash.Query{
+		From:      time.Now().Add(-time.Hour),
+		To:        time.Now().Add(time.Hour),
+		Code:      rep.Code[:4],
+		MinStatus: 400,
+		MaxStatus: 499,
+		Page:      10,
+	}
+
+	if cur, exp := fac.Select(q); s.NoError(exp) {
+		s.NotEmpty(cur.ID())
+
+		if mods, exp := cur.NextPage(0); s.NoError(exp) && s.Len(mods, 1) {
+			s.Equal(rep.ID, mods[0].Export().ID)
+		}
+
+		s.True(cur.Empty())
+
+		// Загруженный курсор продолжает с той же позиции и с теми же фильтрами
+		if cur2, exp := fac.Cursor(cur.ID()); s.NoError(exp) {
+			if mods, exp := cur2.NextPage(10); s.NoError(exp) {
+				s.Empty(mods)
+			}
+		}
+
+		if s.NoError(cur.Close()) {
+			if _, exp := fac.Cursor(cur.ID()); s.Error(exp) {
+				s.True(errx.Is(exp, crash.ErrNotFound))
+			}
+		}
+	}
+
+	// Не пройдет по статусу
+	q.MinStatus = 500
+	q.MaxStatus = 599
+
+	if cur, exp := fac.Select(q); s.NoError(exp) {
+		if mods, exp := cur.NextPage(10); s.NoError(exp) {
+			s.Empty(mods)
+		}
+	}
+
+	// Перепутаны границы статусов
+	q.MinStatus = 600
+
+	if _, exp := fac.Select(q); s.Error(exp) {
+		s.True(errx.Is(exp, crash.ErrQueryValidate))
+	}
+
+	// Неизвестный порядок перебора
+	q.MinStatus = 400
+	q.MaxStatus = 499
+	q.Order = crash.Order(7)
+
+	if _, exp := fac.Select(q); s.Error(exp) {
+		s.True(errx.Is(exp, crash.ErrQueryValidate))
+	}
+
+	// Код целиком ищется в интервале дат индекса, его начало так уже не найдется
+	q.Order = crash.OrderAsc
+	q.CodeExact = true
+
+	if cur, exp := fac.Select(q); s.NoError(exp) {
+		if mods, exp := cur.NextPage(10); s.NoError(exp) {
+			s.Empty(mods)
+		}
+	}
+
+	q.Code = rep.Code
+
+	if cur, exp := fac.Select(q); s.NoError(exp) {
+		if mods, exp := cur.NextPage(10); s.NoError(exp) && s.Len(mods, 1) {
+			s.Equal(rep.ID, mods[0].Export().ID)
+		}
+	}
+
+	// Истекший курсор отличается от несуществующего
+	defer func(ttl time.Duration) { crash.CursorTTL = ttl }(crash.CursorTTL)
+	crash.CursorTTL = -time.Second
+
+	if cur, exp := fac.Select(q); s.NoError(exp) {
+		if _, exp = fac.Cursor(cur.ID()); s.Error(exp) {
+			s.True(errx.Is(exp, crash.ErrExpired))
+			s.True(errx.Is(exp, crash.ErrNotFound))
+		}
+	}
+}
+
+func (s *InterfaceSuite) checkIssues(dbc db.Connection, rep *crash.Report) {
+	// Кол-во и последние отчеты проблемы видны только после фиксации, поэтому каждый шаг в своей транзакции
+	step := func(hdl func(fac crash.Factory)) {
+		tx, err := mvcc.Begin(dbc)
+		s.Require().NoError(err)
+		defer tx.Cancel()
+
+		hdl(crash.NewFdbxFactory(tx, 0x4321))
+		s.Require().NoError(tx.Commit())
+	}
+
+	// Та же ошибка еще раз, но с другим идентификатором
+	rep2 := s.crp.Report(journal.ErrTest.WithReason(errx.ErrForbidden))
+	s.Require().Equal(rep.Fingerprint(), rep2.Fingerprint())
+
+	// И совсем другая
+	rep3 := s.crp.Report(errx.ErrInternal.WithDetail("id %d", 42))
+
+	step(func(fac crash.Factory) {
+		s.Require().NoError(fac.New().Import(rep2))
+		s.Require().NoError(fac.New().Import(rep3))
+	})
+
+	// Порядок списков перестраивается отдельно от сохранения отчетов
+	s.Require().NoError(crash.SortFdbxIssues(dbc, 0x4321))
+
+	step(func(fac crash.Factory) {
+		if iss, exp := fac.Issue(rep.Fingerprint()); s.NoError(exp) {
+			s.Equal(uint64(2), iss.Count)
+			s.Equal(rep.Code, iss.Code)
+			s.Equal([]string{rep2.ID, rep.ID}, iss.Samples)
+			s.True(iss.FirstSeen.Equal(rep.Created))
+			s.True(iss.LastSeen.Equal(rep2.Created))
+		}
+
+		if list, exp := fac.Issues(crash.IssueByRecent, 10); s.NoError(exp) && s.Len(list, 2) {
+			s.Equal(rep3.Fingerprint(), list[0].Fingerprint)
+		}
+
+		if list, exp := fac.Issues(crash.IssueByCount, 1); s.NoError(exp) && s.Len(list, 1) {
+			s.Equal(rep.Fingerprint(), list[0].Fingerprint)
+			s.Equal(uint64(2), list[0].Count)
+		}
+
+		if _, exp := fac.Issue("unknown"); s.Error(exp) {
+			s.True(errx.Is(exp, crash.ErrNotFound))
+		}
+	})
+
+	// Разбор проблемы, исправленные не попадают в открытые
+	step(func(fac crash.Factory) {
+		if iss, exp := fac.SetIssueState(rep.Fingerprint(), crash.IssueResolved, "user", "исправлено"); s.NoError(exp) {
+			s.True(iss.State == crash.IssueResolved)
+			s.Len(iss.History, 1)
+		}
+
+		if _, exp := fac.SetIssueState(rep.Fingerprint(), crash.IssueAcknowledged, "user", ""); s.Error(exp) {
+			s.True(errx.Is(exp, crash.ErrTransition))
+		}
+
+		if list, exp := fac.OpenIssues(rep.Service, 10); s.NoError(exp) && s.Len(list, 1) {
+			s.Equal(rep3.Fingerprint(), list[0].Fingerprint)
+		}
+
+		if list, exp := fac.OpenIssues("unknown", 10); s.NoError(exp) {
+			s.Empty(list)
+		}
+	})
+
+	// Повторение после исправления снова открывает проблему
+	step(func(fac crash.Factory) {
+		s.Require().NoError(fac.New().Import(s.crp.Report(journal.ErrTest.WithReason(errx.ErrForbidden))))
+	})
+
+	step(func(fac crash.Factory) {
+		// Повторившаяся проблема видна сразу, но в открытые попадет после перестроения порядка
+		if iss, exp := fac.Issue(rep.Fingerprint()); s.NoError(exp) {
+			s.Equal(uint64(3), iss.Count)
+		}
+
+		if list, exp := fac.OpenIssues(rep.Service, 10); s.NoError(exp) {
+			s.Len(list, 1)
+		}
+	})
+
+	s.Require().NoError(crash.SortFdbxIssues(dbc, 0x4321))
+
+	step(func(fac crash.Factory) {
+		if iss, exp := fac.Issue(rep.Fingerprint()); s.NoError(exp) {
+			s.True(iss.State == crash.IssueRegressed)
+			s.Equal(uint64(3), iss.Count)
+			s.Len(iss.History, 2)
+		}
+
+		if list, exp := fac.OpenIssues(rep.Service, 10); s.NoError(exp) {
+			s.Len(list, 2)
+		}
+
+		if iss, exp := fac.AssignIssue(rep.Fingerprint(), "owner", "user"); s.NoError(exp) {
+			s.Equal("owner", iss.Owner)
+		}
+
+		if _, exp := fac.NoteIssue(rep.Fingerprint(), "user", ""); s.Error(exp) {
+			s.True(errx.Is(exp, crash.ErrTriageValidate))
+		}
+	})
+}
+
+func (s *InterfaceSuite) checkAggregate(fac journal.Factory) {
+	ctx := context.Background()
+	now := time.Now()
+
+	q := &journal.AggQuery{
+		From: now.Add(-time.Hour),
+		To:   now.Add(time.Hour),
+	}
+
+	// Весь интервал одной точкой
+	if list, exp := fac.Aggregate(ctx, q); s.NoError(exp) && s.Len(list, 1) && s.Len(list[0].Points, 1) {
+		pnt := list[0].Points[0]
+		s.Equal(uint64(3), pnt.Count)
+		s.Equal(uint64(1), pnt.Crashes)
+		s.InDelta(1.0/3, pnt.Rate, 1e-9)
+		s.True(pnt.P50 > 0)
+		s.True(pnt.P99 >= pnt.P50)
+	}
+
+	// По минутам, в ряду есть и пустые точки
+	q.Step = time.Minute
+
+	if list, exp := fac.Aggregate(ctx, q); s.NoError(exp) && s.Len(list, 1) && s.Len(list[0].Points, 121) {
+		var cnt uint64
+
+		for _, pnt := range list[0].Points {
+			cnt += pnt.Count
+		}
+
+		s.Equal(uint64(3), cnt)
+	}
+
+	// Отдельный ряд по каждому названию
+	q.ByName = true
+
+	if list, exp := fac.Aggregate(ctx, q); s.NoError(exp) {
+		s.Len(list, 3)
+	}
+
+	// Чужой сервис
+	q.Services = []string{"unknown"}
+
+	if list, exp := fac.Aggregate(ctx, q); s.NoError(exp) {
+		s.Empty(list)
+	}
+
+	// Слишком много точек
+	q.Step = time.Nanosecond
+
+	if _, exp := fac.Aggregate(ctx, q); s.Error(exp) {
+		s.True(errx.Is(exp, journal.ErrValidate))
+	}
+}
+
+func (s *InterfaceSuite) checkRollup(dbc db.Connection, tx mvcc.Tx) {
+	drv := journal.NewFdbxDriver(dbc, 0x2234, 0x4321, journal.FdbxRollups())
+	fac := journal.NewFdbxFactory(tx, 0x2234, 0x4321, journal.FdbxRollups())
+
+	s.saveEntries(drv, journal.ProviderTemplates())
+	ctx := context.Background()
+	day := s.entry.Start.UTC().Truncate(24 * time.Hour)
+
+	q := &journal.AggQuery{
+		From: day,
+		To:   day.Add(24*time.Hour - time.Nanosecond),
+	}
+
+	// Посуточная свертка
+	if list, exp := fac.Aggregate(ctx, q); s.NoError(exp) && s.Len(list, 1) && s.Len(list[0].Points, 1) {
+		pnt := list[0].Points[0]
+		s.Equal(uint64(3), pnt.Count)
+		s.Equal(uint64(1), pnt.Crashes)
+		s.True(pnt.P99 >= pnt.P50)
+	}
+
+	// Тексты записей разные, но в свертке они учитываются по шаблону
+	q.ByName = true
+	q.ByTemplate = true
+
+	if list, exp := fac.Aggregate(ctx, q); s.NoError(exp) && s.Len(list, 1) {
+		s.Equal("ololo %s %d", list[0].Name)
+		s.Equal(uint64(3), list[0].Points[0].Count)
+	}
+
+	// Поминутная свертка должна совпадать с перебором записей
+	q.Step = time.Minute
+	full := journal.NewFdbxFactory(tx, 0x2234, 0x4321)
+
+	if list, exp := fac.Aggregate(ctx, q); s.NoError(exp) {
+		if rows, exp := full.Aggregate(ctx, q); s.NoError(exp) {
+			s.Equal(rows, list)
+		}
+	}
+
+	// Часть суток не совпадает с корзинами, поэтому записи перебираются целиком
+	q.Step = 0
+	q.To = s.entry2.Start
+
+	if list, exp := fac.Aggregate(ctx, q); s.NoError(exp) {
+		if rows, exp := full.Aggregate(ctx, q); s.NoError(exp) {
+			s.Equal(rows, list)
+		}
+	}
+}
+
+func (s *InterfaceSuite) checkLifetime(fac journal.Factory) {
+	var exp error
+	var cur journal.Cursor
+
+	from := time.Now().Add(-time.Hour)
+	to := time.Now().Add(time.Hour)
+
+	// Закрытый курсор больше не загружается
+	if cur, exp = fac.ByDate(from, to, 10); s.NoError(exp) {
+		s.NoError(cur.Close())
+		s.True(cur.Empty())
+
+		if _, exp = fac.Cursor(cur.ID()); s.Error(exp) {
+			s.True(errx.Is(exp, errx.ErrNotFound))
+			s.False(errx.Is(exp, journal.ErrExpired))
+		}
+	}
+
+	// Истекший курсор отличается от несуществующего
+	defer func(ttl time.Duration) { journal.CursorTTL = ttl }(journal.CursorTTL)
+	journal.CursorTTL = -time.Second
+
+	if cur, exp = fac.ByModelDate(s.mt, "eventID", from, to, 10); s.NoError(exp) {
+		if _, exp = fac.Cursor(cur.ID()); s.Error(exp) {
+			s.True(errx.Is(exp, journal.ErrExpired))
+			s.True(errx.Is(exp, errx.ErrNotFound))
+		}
+	}
+}
+
+func (s *InterfaceSuite) saveEntries(drv journal.Driver, opts ...journal.ProviderOption) (journal.Provider, *crash.Report) {
+	log := journal.NewProvider(1, s.crp, drv, nil, "", opts...)
 	log2 := log.Clone()
 	log3 := log.Clone()
 
@@ -110,6 +986,11 @@ func (s *InterfaceSuite) saveEntries(drv journal.Driver) (journal.Provider, *cra
 	log2.Model(s.mt, "eventID", "some %s", "comment2")
 	log3.Model(s.mt, "eventID", "some %s", "comment3")
 
+	// Подробная отметка, которую можно скрыть от внешних клиентов
+	if log2.(journal.Verbose).V(1) {
+		log2.Print("verbose %s", "comment")
+	}
+
 	// Должны записать данные о модели с ошибкой
 	rep := log.Crash(journal.ErrTest.WithReason(errx.ErrForbidden))
 
@@ -128,6 +1009,15 @@ func (s *InterfaceSuite) checkSaved(fac journal.Factory, log journal.Provider, r
 	mod, err := fac.ByID(s.entry.ID)
 	s.Require().NoError(err)
 
+	// Краткое представление доступно до разбора всей записи
+	if sum := mod.ExportSummary(); s.NotNil(sum) {
+		s.Equal(s.entry.ID, sum.ID)
+		s.Equal(s.entry.Service, sum.Service)
+		s.Equal(s.entry.Total, sum.Total)
+		s.True(s.entry.Start.Equal(sum.Start))
+		s.Equal("ololo test1 41", sum.Name)
+	}
+
 	// По крайней мере, их внешние представления должны совпадать
 	if row, err := mod.Export(true); s.NoError(err) {
 		s.Require().Equal(s.entry, row)
@@ -137,6 +1027,52 @@ func (s *InterfaceSuite) checkSaved(fac journal.Factory, log journal.Provider, r
 		s.Equal(row.ID, api.ID)
 		s.Equal(row.Total, api.Total)
 		s.Equal("ololo test1 41", api.Name)
+		s.Len(api.Stages, 4)
+		s.Equal("", api.Stages[1].Type)
+		s.Equal("event", api.Stages[2].Type)
+		s.Equal("eventID", api.Stages[2].EnID)
+
+		// Без настроек отчеты не загружаются
+		s.Equal("crash", api.Stages[3].Type)
+		s.Equal(rep.ID, api.Stages[3].EnID)
+		s.Nil(api.Stages[3].Fail)
+
+		// Для ошибки краткий отчет
+		api, err := mod.ExportAPIWithOptions(log, journal.APIWithCrash())
+		s.Require().NoError(err)
+
+		if s.Equal("crash", api.Stages[3].Type) && s.NotNil(api.Stages[3].Fail) {
+			s.Equal(rep.ID, api.Stages[3].EnID)
+			s.Equal(rep.Code, api.Stages[3].Fail.Code)
+			s.Equal(rep.Status, api.Stages[3].Fail.Status)
+		}
+
+		// Внутренние модели можно скрыть
+		api, err = mod.ExportAPIWithOptions(log, journal.APIHideTypes(s.mt))
+		s.Require().NoError(err)
+		s.Equal("", api.Stages[2].Type)
+		s.Equal("", api.Stages[2].EnID)
+		s.Equal("crash", api.Stages[3].Type)
+
+		// Скрытие ссылки на отчет не убирает сам краткий отчет
+		api, err = mod.ExportAPIWithOptions(log, journal.APIWithCrash(), journal.APIHideTypes(journal.ModelTypeCrash))
+		s.Require().NoError(err)
+		s.Equal("", api.Stages[3].Type)
+		s.Equal("", api.Stages[3].EnID)
+
+		if s.NotNil(api.Stages[3].Fail) {
+			s.Equal(rep.Code, api.Stages[3].Fail.Code)
+		}
+
+		// Подробные отметки можно скрыть, их время переходит к следующим
+		if mod2, err := fac.ByID(s.entry2.ID); s.NoError(err) {
+			s.Len(mod2.ExportAPI(log).Stages, 3)
+
+			if api, err = mod2.ExportAPIWithOptions(log, journal.APIMaxVerbose(0)); s.NoError(err) && s.Len(api.Stages, 2) {
+				s.Equal("ololo test2 42", api.Name)
+				s.Equal("some comment2", api.Stages[1].Name)
+			}
+		}
 
 		// Сравним, как это выгружается в формате мониторинга
 		mon := mod.ExportMonitoring(log)
@@ -158,6 +1094,33 @@ func (s *InterfaceSuite) checkSaved(fac journal.Factory, log journal.Provider, r
 		}
 	}
 
+	// Пакетная загрузка в порядке запроса, ненайденные отдельно
+	lost := typex.NewUUID().Hex()
+	if mods, miss, exp := fac.ByIDs(s.entry3.ID, lost, s.entry.ID); s.NoError(exp) && s.Len(mods, 2) {
+		s.Equal([]string{lost}, miss)
+
+		if row, err := mods[0].Export(true); s.NoError(err) {
+			s.Equal(s.entry3, row)
+		}
+		if row, err := mods[1].Export(true); s.NoError(err) {
+			s.Equal(s.entry, row)
+		}
+	}
+	if _, _, exp = fac.ByIDs(s.entry.ID, "bad id"); s.Error(exp) {
+		s.True(errx.Is(exp, journal.ErrValidate))
+	}
+
+	// Кол-во записей по модели, без загрузки самих записей
+	if cnt, exp := fac.ByModelCount(journal.ModelTypeCrash, rep.ID); s.NoError(exp) {
+		s.Equal(uint64(1), cnt)
+	}
+	if cnt, exp := fac.ByModelCount(s.mt, "eventID"); s.NoError(exp) {
+		s.Equal(uint64(3), cnt)
+	}
+	if cnt, exp := fac.ByModelCount(s.mt, "unknownID"); s.NoError(exp) {
+		s.Zero(cnt)
+	}
+
 	// Попробуем найти по дате
 	from := time.Now().Add(-time.Hour)
 	to := time.Now().Add(time.Hour)
@@ -212,6 +1175,140 @@ func (s *InterfaceSuite) checkCursor(fac journal.Factory, cid string) {
 	s.True(cur.Empty())
 }
 
+func (s *InterfaceSuite) checkSeek(fac journal.Factory) {
+	var tok string
+	var exp error
+	var cur journal.Cursor
+
+	from := time.Now().Add(-time.Hour)
+	to := time.Now().Add(time.Hour)
+
+	// Сохраненный курсор в прямом порядке
+	if cur, exp = fac.ByDateOrder(journal.OrderAsc, from, to, 10); s.NoError(exp) {
+		if mods, exp := cur.NextPage(10); s.NoError(exp) && s.Len(mods, 3) {
+			if row, err := mods[0].Export(true); s.NoError(err) {
+				s.Equal(s.entry, row)
+			}
+			if row, err := mods[2].Export(true); s.NoError(err) {
+				s.Equal(s.entry3, row)
+			}
+		}
+		s.NotEmpty(cur.Token())
+	}
+
+	// Краткое представление страницы
+	if cur, exp = fac.SeekDate(journal.OrderAsc, from, to, ""); s.NoError(exp) {
+		if sums, exp := cur.NextSummary(10); s.NoError(exp) && s.Len(sums, 3) {
+			s.Equal(s.entry.ID, sums[0].ID)
+			s.Equal(s.entry2.ID, sums[1].ID)
+			s.Equal(s.entry3.ID, sums[2].ID)
+		}
+	}
+
+	// Пакетная выгрузка с отчетами об ошибках
+	if cur, exp = fac.SeekDate(journal.OrderAsc, from, to, ""); s.NoError(exp) {
+		if rows, exp := cur.NextExport(10, journal.ExportCrash); s.NoError(exp) && s.Len(rows, 3) {
+			s.Equal(s.entry, rows[0])
+			s.Equal(s.entry2, rows[1])
+			s.Equal(s.entry3, rows[2])
+		}
+	}
+
+	// Краткие отчеты об ошибках, без цепочки
+	if mod, exp := fac.ByID(s.entry.ID); s.NoError(exp) {
+		if rows, exp := fac.Export(journal.ExportCrashBrief, mod); s.NoError(exp) && s.Len(rows, 1) {
+			if fail := rows[0].Chain[3].Fail; s.NotNil(fail) {
+				s.Equal(s.entry.Chain[3].Fail.Code, fail.Code)
+				s.Equal(s.entry.Chain[3].Fail.Title, fail.Title)
+				s.Equal(s.entry.Chain[3].Fail.Status, fail.Status)
+				s.Empty(fail.Entries)
+			}
+		}
+		if rows, exp := fac.Export(journal.ExportNoCrash, mod); s.NoError(exp) && s.Len(rows, 1) {
+			s.Nil(rows[0].Chain[3].Fail)
+		}
+	}
+
+	// Некорректный маркер
+	if _, exp = fac.SeekDate(journal.OrderAsc, from, to, "!"); s.Error(exp) {
+		s.True(errx.Is(exp, errx.ErrBadRequest))
+	}
+
+	// Неизвестный порядок перебора
+	if _, exp = fac.SeekDate(journal.Order(7), from, to, ""); s.Error(exp) {
+		s.True(errx.Is(exp, journal.ErrValidate))
+	}
+
+	if _, exp = fac.ByDateOrder(journal.Order(7), from, to, 10); s.Error(exp) {
+		s.True(errx.Is(exp, journal.ErrValidate))
+	}
+
+	// Сервисы, указанные при создании курсора
+	if cur, exp = fac.SeekDate(journal.OrderAsc, from, to, "", "unknown"); s.NoError(exp) {
+		if mods, exp := cur.NextPage(10); s.NoError(exp) {
+			s.Empty(mods)
+		}
+	}
+
+	if cur, exp = fac.SeekModelDate(journal.OrderAsc, s.mt, "eventID", from, to, "", "unknown"); s.NoError(exp) {
+		if mods, exp := cur.NextPage(10); s.NoError(exp) {
+			s.Empty(mods)
+		}
+	}
+
+	if cur, exp = fac.ByDateOrder(journal.OrderAsc, from, to, 10, "unknown"); s.NoError(exp) {
+		if mods, exp := cur.NextPage(10); s.NoError(exp) {
+			s.Empty(mods)
+		}
+	}
+
+	// Постраничный перебор по маркеру, в прямом порядке
+	for i, entry := range []*journal.Entry{s.entry, s.entry2, s.entry3} {
+		if cur, exp = fac.SeekDate(journal.OrderAsc, from, to, tok); s.NoError(exp) {
+			s.Empty(cur.ID())
+
+			if mods, exp := cur.NextPage(1); s.NoError(exp) && s.Len(mods, 1, i) {
+				if row, err := mods[0].Export(true); s.NoError(err) {
+					s.Equal(entry, row)
+				}
+			}
+
+			tok = cur.Token()
+		}
+	}
+
+	if cur, exp = fac.SeekDate(journal.OrderAsc, from, to, tok); s.NoError(exp) {
+		if mods, exp := cur.NextPage(1); s.NoError(exp) {
+			s.Empty(mods)
+		}
+		s.True(cur.Empty())
+	}
+
+	// Постраничный перебор по маркеру, в обратном порядке
+	tok = ""
+	for i, entry := range []*journal.Entry{s.entry3, s.entry2} {
+		if cur, exp = fac.SeekModelDate(journal.OrderDesc, s.mt, "eventID", from, to, tok); s.NoError(exp) {
+			if mods, exp := cur.NextPage(1); s.NoError(exp) && s.Len(mods, 1, i) {
+				if row, err := mods[0].Export(true); s.NoError(err) {
+					s.Equal(entry, row)
+				}
+			}
+
+			tok = cur.Token()
+		}
+	}
+
+	// Тот же курсор можно использовать и дальше, без пересоздания
+	if cur, exp = fac.SeekModelDate(journal.OrderDesc, s.mt, "eventID", from, to, tok); s.NoError(exp) {
+		if mods, exp := cur.NextPage(5); s.NoError(exp) && s.Len(mods, 1) {
+			if row, err := mods[0].Export(true); s.NoError(err) {
+				s.Equal(s.entry, row)
+			}
+		}
+		s.True(cur.Empty())
+	}
+}
+
 type mType struct {
 	id   int
 	name string
@@ -0,0 +1,73 @@
+// Package batch - загрузка записей по списку идентификаторов, общая для журнала и отчетов об ошибках
+package batch
+
+import "sync"
+
+// Parallel - сколько записей загружается одновременно
+const Parallel = 16
+
+/*
+	Load - загрузка n записей, не больше Parallel одновременно.
+
+	* load - загрузка записи с номером i, если запись не найдена, возвращает false без ошибки
+	* Каждая выборка идет в своей физической транзакции, поэтому их можно делать параллельно
+
+	* Возвращает номера ненайденных записей по порядку
+	* Если какая-то загрузка вернула ошибку, остальные не запускаются и возвращается первая по порядку
+*/
+func Load(n int, load func(i int) (bool, error)) (miss []int, err error) {
+	var wg sync.WaitGroup
+	var mx sync.Mutex
+	var stop bool
+
+	found := make([]bool, n)
+	errs := make([]error, n)
+	jobs := make(chan int)
+
+	workers := Parallel
+	if n < workers {
+		workers = n
+	}
+
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				if found[i], errs[i] = load(i); errs[i] != nil {
+					mx.Lock()
+					stop = true
+					mx.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		mx.Lock()
+		done := stop
+		mx.Unlock()
+
+		if done {
+			break
+		}
+
+		jobs <- i
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+
+		if !found[i] {
+			miss = append(miss, i)
+		}
+	}
+
+	return miss, nil
+}
@@ -0,0 +1,63 @@
+package batch
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func TestBatch(t *testing.T) {
+	suite.Run(t, new(BatchSuite))
+}
+
+type BatchSuite struct {
+	suite.Suite
+}
+
+func (s *BatchSuite) TestLoad() {
+	var cur, max int32
+
+	miss, err := Load(10*Parallel, func(i int) (bool, error) {
+		if n := atomic.AddInt32(&cur, 1); n > atomic.LoadInt32(&max) {
+			atomic.StoreInt32(&max, n)
+		}
+
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&cur, -1)
+		return i%50 != 7, nil
+	})
+
+	s.NoError(err)
+	s.Equal([]int{7, 57, 107, 157}, miss)
+	s.LessOrEqual(atomic.LoadInt32(&max), int32(Parallel))
+}
+
+func (s *BatchSuite) TestError() {
+	var cnt int32
+
+	fail := errors.New("fail")
+
+	_, err := Load(100*Parallel, func(i int) (bool, error) {
+		atomic.AddInt32(&cnt, 1)
+
+		if i == 3 {
+			return false, fail
+		}
+
+		time.Sleep(time.Millisecond)
+		return true, nil
+	})
+
+	// После ошибки оставшиеся записи не загружаются
+	s.Equal(fail, err)
+	s.Less(atomic.LoadInt32(&cnt), int32(100*Parallel))
+}
+
+func (s *BatchSuite) TestEmpty() {
+	miss, err := Load(0, func(int) (bool, error) { return false, errors.New("fail") })
+	s.NoError(err)
+	s.Empty(miss)
+}
@@ -0,0 +1,290 @@
+// Package search - полнотекстовый индекс слов, общий для журнала и отчетов об ошибках
+package search
+
+import (
+	"bytes"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/shestakovda/fdbx/v2"
+	"github.com/shestakovda/fdbx/v2/mvcc"
+	"github.com/shestakovda/fdbx/v2/orm"
+	"github.com/shestakovda/typex"
+)
+
+// Максимальная длина слова в индексе, более длинные обрезаются
+const maxTokenLen = 32
+
+// Pack - кол-во ключей индекса слов, загружаемых за один раз
+const Pack = 100
+
+/*
+	Tokenize - разбиение текста на уникальные слова для полнотекстового индекса.
+
+	* Словом считается последовательность букв и цифр любого алфавита
+	* Слова приводятся к нижнему регистру, слова из одного символа отбрасываются
+	* Слишком длинные слова обрезаются по границе символа
+*/
+func Tokenize(text string) []string {
+	uniq := make(map[string]struct{})
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	res := make([]string, 0, len(words))
+
+	for _, word := range words {
+		if utf8.RuneCountInString(word) < 2 {
+			continue
+		}
+
+		if len(word) > maxTokenLen {
+			cut := maxTokenLen
+			for !utf8.RuneStart(word[cut]) {
+				cut--
+			}
+			word = word[:cut]
+		}
+
+		if _, ok := uniq[word]; !ok {
+			uniq[word] = struct{}{}
+			res = append(res, word)
+		}
+	}
+
+	return res
+}
+
+/*
+	Match - все слова запроса являются началом каких-то слов текста.
+
+	* query - слова запроса, полученные из Tokenize
+	* tokens - слова текста, полученные из Tokenize
+*/
+func Match(query, tokens []string) bool {
+	for _, q := range query {
+		found := false
+
+		for _, t := range tokens {
+			if strings.HasPrefix(t, q) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Key - ключ индекса слов: слово, разделитель для поиска по началу слова и время записи
+func Key(token string, start []byte) []byte {
+	res := make([]byte, 0, len(token)+1+len(start))
+	res = append(res, token...)
+	res = append(res, 0)
+	return append(res, start...)
+}
+
+// ParseKey - разбор ключа индекса слов без префикса orm: слово, время записи и ее идентификатор
+func ParseKey(buf []byte) (word string, start time.Time, uid typex.UUID, ok bool) {
+	i := bytes.IndexByte(buf, 0)
+
+	if i < 0 || len(buf) < i+9 {
+		return "", time.Time{}, nil, false
+	}
+
+	start, _ = fdbx.Byte2Time(buf[i+1 : i+9])
+	return string(buf[:i]), start, typex.UUID(buf[i+9:]), true
+}
+
+/*
+	Scan - перебор индекса слов по самому длинному слову запроса в интервале дат.
+
+	* Index - номер индекса слов в таблице
+	* Tokens - слова записи по ее значению, как при построении индекса
+	* Filter - дополнительная проверка записи, может быть пустой
+
+	* После начала слова в ключе сразу идет дата, поэтому для каждого слова индекса
+	* перебор сразу переходит к началу интервала, а после его конца - к следующему слову
+	* Одно слово запроса может быть началом нескольких слов записи, поэтому запись выдается
+	* только на том слове индекса, которое меньше всех остальных подходящих
+*/
+type Scan struct {
+	Index  uint16
+	Words  []string
+	From   time.Time
+	Last   time.Time
+	Tokens func(val []byte) []string
+	Filter func(row fdbx.Pair) (bool, error)
+}
+
+/*
+	Next - следующие записи, подходящие под запрос.
+
+	* pos - ключ индекса без префикса orm, с которого продолжается перебор, пустой для начала
+	* size - сколько записей нужно найти
+
+	* Возвращает записи с идентификатором в ключе, позицию для следующего вызова
+	* и признак того, что интервал слов закончился
+*/
+func (s *Scan) Next(tx mvcc.Tx, tbl orm.Table, pos []byte, size int) (res []fdbx.Pair, _ []byte, done bool, err error) {
+	var keys []fdbx.Pair
+	var rows []fdbx.Pair
+
+	drv, stop := s.bounds()
+	res = make([]fdbx.Pair, 0, size)
+
+	if pos == nil {
+		pos = []byte(drv)
+	}
+
+	for len(res) < size {
+		if keys, err = tx.ListAll(
+			mvcc.From(orm.WrapIndexKey(tbl.ID(), s.Index, fdbx.Bytes2Key(pos))),
+			mvcc.Last(orm.WrapIndexKey(tbl.ID(), s.Index, fdbx.Bytes2Key(stop))),
+			mvcc.Limit(Pack),
+		); err != nil {
+			return nil, nil, false, err
+		}
+
+		if len(keys) == 0 {
+			return res, pos, true, nil
+		}
+
+		// Ключи вне интервала дат не загружаются, перебор продолжается с ближайшего подходящего
+		hits := make([]hit, 0, len(keys))
+		seek := []byte(nil)
+
+		for i := range keys {
+			word, uid, next, ok := s.step(orm.UnwrapIndexKey(keys[i].Key()).Clone().Bytes())
+
+			if !ok {
+				seek = next
+				break
+			}
+
+			hits = append(hits, hit{word: word, uid: uid, next: next})
+		}
+
+		if rows, err = s.load(tx, tbl, hits); err != nil {
+			return nil, nil, false, err
+		}
+
+		found := make(map[string]fdbx.Pair, len(rows))
+		for i := range rows {
+			found[string(rows[i].Key().Bytes())] = rows[i]
+		}
+
+		for i := range hits {
+			if len(res) >= size {
+				return res, pos, false, nil
+			}
+
+			pos = hits[i].next
+			row, ok := found[string(hits[i].uid)]
+
+			if !ok {
+				continue
+			}
+
+			if ok, err = s.match(row, hits[i].word, drv); err != nil {
+				return nil, nil, false, err
+			}
+
+			if ok {
+				res = append(res, row)
+			}
+		}
+
+		if seek != nil {
+			pos = seek
+		} else if len(keys) < Pack {
+			return res, pos, true, nil
+		}
+	}
+
+	return res, pos, false, nil
+}
+
+// hit - ключ индекса в интервале дат
+type hit struct {
+	word string
+	uid  typex.UUID
+	next []byte
+}
+
+// bounds - границы перебора по началу самого длинного слова запроса, байт 0xFF не встречается в UTF-8
+func (s *Scan) bounds() (drv string, stop []byte) {
+	for _, q := range s.Words {
+		if len(q) > len(drv) {
+			drv = q
+		}
+	}
+
+	return drv, append([]byte(drv), 0xFF)
+}
+
+/*
+	step - разбор ключа индекса без префикса orm.
+
+	* Если дата ключа в интервале, ok и next - позиция сразу после ключа
+	* Если дата раньше интервала, next - начало интервала у того же слова
+	* Если дата позже интервала, next - следующее слово, в словах нет байта 0x01
+*/
+func (s *Scan) step(buf []byte) (word string, uid typex.UUID, next []byte, ok bool) {
+	var start time.Time
+
+	if word, start, uid, ok = ParseKey(buf); !ok {
+		return "", nil, append(buf, 0), false
+	}
+
+	if start.Before(s.From) {
+		return word, uid, Key(word, fdbx.Time2Byte(s.From)), false
+	}
+
+	if start.After(s.Last) {
+		return word, uid, append([]byte(word), 1), false
+	}
+
+	return word, uid, append(buf, 0), true
+}
+
+func (s *Scan) load(tx mvcc.Tx, tbl orm.Table, hits []hit) ([]fdbx.Pair, error) {
+	if len(hits) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]fdbx.Key, len(hits))
+
+	for i := range hits {
+		ids[i] = fdbx.Bytes2Key(hits[i].uid)
+	}
+
+	return tbl.Select(tx).ByID(ids...).All()
+}
+
+// match - запись подходит под запрос и выдается на этом слове индекса впервые
+func (s *Scan) match(row fdbx.Pair, word, drv string) (bool, error) {
+	words := s.Tokens(row.Value())
+
+	if !Match(s.Words, words) {
+		return false, nil
+	}
+
+	for i := range words {
+		if words[i] < word && strings.HasPrefix(words[i], drv) {
+			return false, nil
+		}
+	}
+
+	if s.Filter != nil {
+		return s.Filter(row)
+	}
+
+	return true, nil
+}
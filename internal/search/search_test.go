@@ -0,0 +1,110 @@
+package search
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shestakovda/fdbx/v2"
+	"github.com/shestakovda/typex"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestSearch(t *testing.T) {
+	suite.Run(t, new(SearchSuite))
+}
+
+type SearchSuite struct {
+	suite.Suite
+}
+
+func (s *SearchSuite) TestTokenize() {
+	s.Equal([]string{"ошибка", "payment", "42"}, Tokenize("Ошибка: payment #42, ошибка x!"))
+	s.Empty(Tokenize("a, b; ы"))
+
+	// Длинные слова обрезаются по границе символа
+	long := Tokenize(strings.Repeat("ж", 40))
+	if s.Len(long, 1) {
+		s.Equal(strings.Repeat("ж", maxTokenLen/2), long[0])
+	}
+}
+
+func (s *SearchSuite) TestMatch() {
+	tokens := Tokenize("Payment gateway timeout")
+
+	s.True(Match(Tokenize("pay time"), tokens))
+	s.True(Match(nil, tokens))
+	s.False(Match(Tokenize("pay lost"), tokens))
+	s.False(Match(Tokenize("ment"), tokens))
+}
+
+func (s *SearchSuite) TestKey() {
+	uid := typex.NewUUID()
+	now := time.Unix(0, time.Now().UnixNano())
+
+	word, start, id, ok := ParseKey(append(Key("слово", fdbx.Time2Byte(now)), uid...))
+
+	if s.True(ok) {
+		s.Equal("слово", word)
+		s.True(now.Equal(start))
+		s.Equal(uid, id)
+	}
+
+	_, _, _, ok = ParseKey([]byte("слово"))
+	s.False(ok)
+
+	_, _, _, ok = ParseKey(Key("слово", []byte{1, 2, 3}))
+	s.False(ok)
+}
+
+func (s *SearchSuite) TestBounds() {
+	scan := &Scan{Words: Tokenize("pay gateway")}
+
+	drv, stop := scan.bounds()
+	s.Equal("gateway", drv)
+	s.Equal(append([]byte("gateway"), 0xFF), stop)
+}
+
+func (s *SearchSuite) TestStep() {
+	uid := typex.NewUUID()
+	from := time.Unix(1000, 0)
+	last := time.Unix(2000, 0)
+	scan := &Scan{From: from, Last: last}
+
+	key := func(sec int64) []byte {
+		return append(Key("word", fdbx.Time2Byte(time.Unix(sec, 0))), uid...)
+	}
+
+	// В интервале - продолжаем сразу после ключа
+	word, id, next, ok := scan.step(key(1500))
+	if s.True(ok) {
+		s.Equal("word", word)
+		s.Equal(uid, id)
+		s.Equal(append(key(1500), 0), next)
+	}
+
+	// Границы интервала включаются
+	_, _, _, ok = scan.step(key(1000))
+	s.True(ok)
+	_, _, _, ok = scan.step(key(2000))
+	s.True(ok)
+
+	// Раньше интервала - переход к его началу у того же слова
+	_, _, next, ok = scan.step(key(500))
+	s.False(ok)
+	s.Equal(Key("word", fdbx.Time2Byte(from)), next)
+	s.True(string(next) > string(key(500)))
+	s.True(string(next) <= string(key(1000)))
+
+	// Позже интервала - переход к следующему слову, длинные слова с тем же началом не пропускаются
+	_, _, next, ok = scan.step(key(2500))
+	s.False(ok)
+	s.Equal([]byte("word\x01"), next)
+	s.True(string(next) > string(key(2500)))
+	s.True(string(next) < string(Key("words", fdbx.Time2Byte(from))))
+
+	// Некорректный ключ просто пропускается
+	_, _, next, ok = scan.step([]byte("word"))
+	s.False(ok)
+	s.Equal([]byte("word\x00"), next)
+}
@@ -0,0 +1,206 @@
+// Package ttl - учет срока жизни сохраненных курсоров, общий для журнала и отчетов об ошибках
+package ttl
+
+import (
+	"context"
+	"time"
+
+	"github.com/shestakovda/errx"
+	"github.com/shestakovda/fdbx/v2"
+	"github.com/shestakovda/fdbx/v2/db"
+	"github.com/shestakovda/fdbx/v2/mvcc"
+	"github.com/shestakovda/typex"
+)
+
+// Состояния элемента очереди истечения
+const (
+	alive byte = 1 // Курсор еще существует, по истечении нужно удалить его данные
+	tomb  byte = 2 // Данные уже удалены, осталось забыть об истекшем курсоре
+)
+
+// SweepPack - максимальное кол-во курсоров, обрабатываемых за одну транзакцию очистки
+const SweepPack = 1000
+
+/*
+	Space - области ключей таблицы для учета срока жизни курсоров.
+
+	* Table - номер таблицы, ключи учета лежат в ней вне областей orm
+	* Meta - область времени истечения по идентификатору курсора
+	* Queue - область очереди курсоров по времени истечения
+	* Data - ключи данных курсора, которые удаляются при закрытии и по истечении
+	* Vacuum - области данных курсоров, в которых после удаления нужно чистить старые версии
+*/
+type Space struct {
+	Table  uint16
+	Meta   byte
+	Queue  byte
+	Data   func(uid typex.UUID) []fdbx.Key
+	Vacuum []byte
+}
+
+// Save - сохранение срока действия нового курсора
+func (s *Space) Save(tx mvcc.Tx, uid typex.UUID, exp time.Time) error {
+	return tx.Upsert([]fdbx.Pair{
+		fdbx.NewPair(s.metaKey(uid), fdbx.Time2Byte(exp)),
+		fdbx.NewPair(s.queueKey(exp, uid), []byte{alive}),
+	})
+}
+
+// Expire - время истечения курсора, у курсоров без срока оно нулевое
+func (s *Space) Expire(tx mvcc.Tx, uid typex.UUID) (exp time.Time, err error) {
+	var row fdbx.Pair
+
+	if row, err = tx.Select(s.metaKey(uid)); err != nil {
+		if errx.Is(err, mvcc.ErrNotFound) {
+			return exp, nil
+		}
+		return exp, err
+	}
+
+	return fdbx.Byte2Time(row.Value())
+}
+
+// Drop - удаление данных закрытого курсора вместе со сведениями о сроке действия, если они есть
+func (s *Space) Drop(tx mvcc.Tx, uid typex.UUID) (err error) {
+	var exp time.Time
+
+	keys := []fdbx.Key{s.metaKey(uid)}
+
+	if s.Data != nil {
+		keys = append(keys, s.Data(uid)...)
+	}
+
+	if exp, err = s.Expire(tx, uid); err != nil {
+		return err
+	}
+
+	if !exp.IsZero() {
+		keys = append(keys, s.queueKey(exp, uid))
+	}
+
+	return tx.Delete(keys)
+}
+
+/*
+	Sweep - один проход очистки истекших курсоров на момент now.
+
+	* grace - сколько после удаления данных истекшего курсора о нем помнить, чтобы отличать его от несуществующего
+*/
+func (s *Space) Sweep(dbc db.Connection, now time.Time, grace time.Duration) (err error) {
+	var cnt int
+	var tx mvcc.Tx
+
+	for cnt = SweepPack; cnt >= SweepPack; {
+		if tx, err = mvcc.Begin(dbc); err != nil {
+			return err
+		}
+
+		if cnt, err = s.sweepPart(tx, now, grace); err != nil {
+			tx.Cancel()
+			return err
+		}
+
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	if tx, err = mvcc.Begin(dbc); err != nil {
+		return err
+	}
+	defer tx.Cancel()
+
+	// Удаленные значения остаются в БД до очистки старых версий
+	for _, ns := range append([]byte{s.Queue, s.Meta}, s.Vacuum...) {
+		if err = tx.Vacuum(Prefix(s.Table, ns)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+	Loop - периодический запуск очистки до отмены контекста.
+
+	* fail - обработчик ошибок очистки, они не прерывают цикл
+*/
+func Loop(ctx context.Context, wait time.Duration, sweep func() error, fail func(error)) {
+	tick := time.NewTicker(wait)
+	defer tick.Stop()
+
+	for ctx.Err() == nil {
+		if err := sweep(); err != nil && fail != nil {
+			fail(err)
+		}
+
+		select {
+		case <-tick.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Space) sweepPart(tx mvcc.Tx, now time.Time, grace time.Duration) (_ int, err error) {
+	var rows []fdbx.Pair
+
+	opts := []mvcc.Option{
+		mvcc.From(Prefix(s.Table, s.Queue)),
+		mvcc.Last(Prefix(s.Table, s.Queue).RPart(fdbx.Time2Byte(now)...)),
+		mvcc.Limit(SweepPack),
+	}
+
+	if rows, err = tx.ListAll(opts...); err != nil {
+		return 0, err
+	}
+
+	drop := make([]fdbx.Key, 0, 2*len(rows))
+	dead := make([]fdbx.Pair, 0, len(rows))
+
+	for i := range rows {
+		key := rows[i].Key().Bytes()
+		val := rows[i].Value()
+
+		if len(key) < 11 || len(val) == 0 {
+			continue
+		}
+
+		exp, _ := fdbx.Byte2Time(key[3:11])
+		uid := typex.UUID(key[11:])
+		drop = append(drop, rows[i].Key().Clone())
+
+		if val[0] == alive {
+			// Сначала удаляем данные курсора, но еще помним, что он истек
+			if s.Data != nil {
+				drop = append(drop, s.Data(uid)...)
+			}
+			dead = append(dead, fdbx.NewPair(s.queueKey(exp.Add(grace), uid), []byte{tomb}))
+		} else {
+			drop = append(drop, s.metaKey(uid))
+		}
+	}
+
+	if err = tx.Delete(drop); err != nil {
+		return 0, err
+	}
+
+	if err = tx.Upsert(dead); err != nil {
+		return 0, err
+	}
+
+	return len(rows), nil
+}
+
+// Prefix - область ключей таблицы вне областей orm
+func Prefix(tbid uint16, ns byte) fdbx.Key {
+	return fdbx.Bytes2Key([]byte{byte(tbid >> 8), byte(tbid), ns})
+}
+
+func (s *Space) metaKey(uid typex.UUID) fdbx.Key {
+	return Prefix(s.Table, s.Meta).RPart(uid...)
+}
+
+func (s *Space) queueKey(exp time.Time, uid typex.UUID) fdbx.Key {
+	return Prefix(s.Table, s.Queue).RPart(append(fdbx.Time2Byte(exp), uid...)...)
+}
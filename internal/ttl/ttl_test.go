@@ -0,0 +1,72 @@
+package ttl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shestakovda/fdbx/v2"
+	"github.com/shestakovda/typex"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestTTL(t *testing.T) {
+	suite.Run(t, new(TTLSuite))
+}
+
+type TTLSuite struct {
+	suite.Suite
+}
+
+func (s *TTLSuite) TestKeys() {
+	uid := typex.NewUUID()
+	now := time.Unix(1000, 0)
+	spc := &Space{Table: 0x4321, Meta: 0x10, Queue: 0x11}
+
+	s.Equal([]byte{0x43, 0x21, 0x10}, Prefix(0x4321, 0x10).Bytes())
+	s.Equal(append([]byte{0x43, 0x21, 0x10}, uid...), spc.metaKey(uid).Bytes())
+
+	// Очередь упорядочена по времени истечения, за ним идентификатор курсора, как его разбирает sweepPart
+	key := spc.queueKey(now, uid).Bytes()
+	exp, _ := fdbx.Byte2Time(key[3:11])
+
+	s.True(now.Equal(exp))
+	s.Equal(uid, typex.UUID(key[11:]))
+	s.Equal(-1, bytes.Compare(key, spc.queueKey(now.Add(time.Nanosecond), typex.NewUUID()).Bytes()))
+
+	// Истекшие на момент проверки курсоры попадают в интервал очистки
+	last := Prefix(spc.Table, spc.Queue).RPart(fdbx.Time2Byte(now)...).Bytes()
+	s.True(bytes.HasPrefix(key, last))
+	s.Equal(1, bytes.Compare(spc.queueKey(now.Add(time.Nanosecond), uid).Bytes(), append(last, 0xFF)))
+}
+
+func (s *TTLSuite) TestLoop() {
+	var errs []error
+
+	cnt := 0
+	fail := errors.New("fail")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Ошибки не прерывают цикл, отмена контекста - прерывает
+	Loop(ctx, time.Millisecond, func() error {
+		if cnt++; cnt == 3 {
+			cancel()
+		}
+		return fail
+	}, func(err error) {
+		errs = append(errs, err)
+	})
+
+	s.Equal(3, cnt)
+	s.Equal([]error{fail, fail, fail}, errs)
+
+	// Без обработчика ошибки просто пропускаются, после отмены проходов нет
+	Loop(ctx, time.Millisecond, func() error {
+		cnt++
+		return fail
+	}, nil)
+
+	s.Equal(3, cnt)
+}
@@ -29,6 +29,8 @@ func (m journalModels) String() string {
 		return "unknown"
 	case ModelTypeCrash:
 		return "crash"
+	case ModelTypeRemoteCrash:
+		return "remote_crash"
 	default:
 		return strconv.Itoa(int(m))
 	}
@@ -38,5 +40,6 @@ func init() {
 	regType(
 		ModelTypeUnknown,
 		ModelTypeCrash,
+		ModelTypeRemoteCrash,
 	)
 }
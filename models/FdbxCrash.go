@@ -13,6 +13,8 @@ type FdbxCrashT struct {
 	Status  uint16
 	Created int64
 	Steps   []*FdbxStepT
+	Service string
+	Trace   string
 }
 
 func (t *FdbxCrashT) Pack(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
@@ -22,6 +24,8 @@ func (t *FdbxCrashT) Pack(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
 	codeOffset := builder.CreateString(t.Code)
 	linkOffset := builder.CreateString(t.Link)
 	titleOffset := builder.CreateString(t.Title)
+	serviceOffset := builder.CreateString(t.Service)
+	traceOffset := builder.CreateString(t.Trace)
 	stepsOffset := flatbuffers.UOffsetT(0)
 	if t.Steps != nil {
 		stepsLength := len(t.Steps)
@@ -42,6 +46,8 @@ func (t *FdbxCrashT) Pack(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
 	FdbxCrashAddStatus(builder, t.Status)
 	FdbxCrashAddCreated(builder, t.Created)
 	FdbxCrashAddSteps(builder, stepsOffset)
+	FdbxCrashAddService(builder, serviceOffset)
+	FdbxCrashAddTrace(builder, traceOffset)
 	return FdbxCrashEnd(builder)
 }
 
@@ -58,6 +64,8 @@ func (rcv *FdbxCrash) UnPackTo(t *FdbxCrashT) {
 		rcv.Steps(&x, j)
 		t.Steps[j] = x.UnPack()
 	}
+	t.Service = string(rcv.Service())
+	t.Trace = string(rcv.Trace())
 }
 
 func (rcv *FdbxCrash) UnPack() *FdbxCrashT {
@@ -157,8 +165,24 @@ func (rcv *FdbxCrash) StepsLength() int {
 	return 0
 }
 
+func (rcv *FdbxCrash) Service() []byte {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(16))
+	if o != 0 {
+		return rcv._tab.ByteVector(o + rcv._tab.Pos)
+	}
+	return nil
+}
+
+func (rcv *FdbxCrash) Trace() []byte {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(18))
+	if o != 0 {
+		return rcv._tab.ByteVector(o + rcv._tab.Pos)
+	}
+	return nil
+}
+
 func FdbxCrashStart(builder *flatbuffers.Builder) {
-	builder.StartObject(6)
+	builder.StartObject(8)
 }
 func FdbxCrashAddCode(builder *flatbuffers.Builder, code flatbuffers.UOffsetT) {
 	builder.PrependUOffsetTSlot(0, flatbuffers.UOffsetT(code), 0)
@@ -178,6 +202,12 @@ func FdbxCrashAddCreated(builder *flatbuffers.Builder, created int64) {
 func FdbxCrashAddSteps(builder *flatbuffers.Builder, steps flatbuffers.UOffsetT) {
 	builder.PrependUOffsetTSlot(5, flatbuffers.UOffsetT(steps), 0)
 }
+func FdbxCrashAddService(builder *flatbuffers.Builder, service flatbuffers.UOffsetT) {
+	builder.PrependUOffsetTSlot(6, flatbuffers.UOffsetT(service), 0)
+}
+func FdbxCrashAddTrace(builder *flatbuffers.Builder, trace flatbuffers.UOffsetT) {
+	builder.PrependUOffsetTSlot(7, flatbuffers.UOffsetT(trace), 0)
+}
 func FdbxCrashStartStepsVector(builder *flatbuffers.Builder, numElems int) flatbuffers.UOffsetT {
 	return builder.StartVector(4, numElems, 4)
 }
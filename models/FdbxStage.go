@@ -7,10 +7,15 @@ import (
 )
 
 type FdbxStageT struct {
-	Dur int64
-	Mtp int32
-	Mid string
-	Msg string
+	Dur    int64
+	Mtp    int32
+	Mid    string
+	Msg    string
+	Verb   int32
+	Code   string
+	Status uint16
+	Tmpl   string
+	Args   []string
 }
 
 func (t *FdbxStageT) Pack(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
@@ -19,11 +24,31 @@ func (t *FdbxStageT) Pack(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
 	}
 	midOffset := builder.CreateString(t.Mid)
 	msgOffset := builder.CreateString(t.Msg)
+	codeOffset := builder.CreateString(t.Code)
+	tmplOffset := builder.CreateString(t.Tmpl)
+	argsOffset := flatbuffers.UOffsetT(0)
+	if t.Args != nil {
+		argsLength := len(t.Args)
+		argsOffsets := make([]flatbuffers.UOffsetT, argsLength)
+		for j := 0; j < argsLength; j++ {
+			argsOffsets[j] = builder.CreateString(t.Args[j])
+		}
+		FdbxStageStartArgsVector(builder, argsLength)
+		for j := argsLength - 1; j >= 0; j-- {
+			builder.PrependUOffsetT(argsOffsets[j])
+		}
+		argsOffset = builder.EndVector(argsLength)
+	}
 	FdbxStageStart(builder)
 	FdbxStageAddDur(builder, t.Dur)
 	FdbxStageAddMtp(builder, t.Mtp)
 	FdbxStageAddMid(builder, midOffset)
 	FdbxStageAddMsg(builder, msgOffset)
+	FdbxStageAddVerb(builder, t.Verb)
+	FdbxStageAddCode(builder, codeOffset)
+	FdbxStageAddStatus(builder, t.Status)
+	FdbxStageAddTmpl(builder, tmplOffset)
+	FdbxStageAddArgs(builder, argsOffset)
 	return FdbxStageEnd(builder)
 }
 
@@ -32,6 +57,15 @@ func (rcv *FdbxStage) UnPackTo(t *FdbxStageT) {
 	t.Mtp = rcv.Mtp()
 	t.Mid = string(rcv.Mid())
 	t.Msg = string(rcv.Msg())
+	t.Verb = rcv.Verb()
+	t.Code = string(rcv.Code())
+	t.Status = rcv.Status()
+	t.Tmpl = string(rcv.Tmpl())
+	argsLength := rcv.ArgsLength()
+	t.Args = make([]string, argsLength)
+	for j := 0; j < argsLength; j++ {
+		t.Args[j] = string(rcv.Args(j))
+	}
 }
 
 func (rcv *FdbxStage) UnPack() *FdbxStageT {
@@ -103,8 +137,65 @@ func (rcv *FdbxStage) Msg() []byte {
 	return nil
 }
 
+func (rcv *FdbxStage) Verb() int32 {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(12))
+	if o != 0 {
+		return rcv._tab.GetInt32(o + rcv._tab.Pos)
+	}
+	return 0
+}
+
+func (rcv *FdbxStage) MutateVerb(n int32) bool {
+	return rcv._tab.MutateInt32Slot(12, n)
+}
+
+func (rcv *FdbxStage) Code() []byte {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(14))
+	if o != 0 {
+		return rcv._tab.ByteVector(o + rcv._tab.Pos)
+	}
+	return nil
+}
+
+func (rcv *FdbxStage) Status() uint16 {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(16))
+	if o != 0 {
+		return rcv._tab.GetUint16(o + rcv._tab.Pos)
+	}
+	return 0
+}
+
+func (rcv *FdbxStage) MutateStatus(n uint16) bool {
+	return rcv._tab.MutateUint16Slot(16, n)
+}
+
+func (rcv *FdbxStage) Tmpl() []byte {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(18))
+	if o != 0 {
+		return rcv._tab.ByteVector(o + rcv._tab.Pos)
+	}
+	return nil
+}
+
+func (rcv *FdbxStage) Args(j int) []byte {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(20))
+	if o != 0 {
+		a := rcv._tab.Vector(o)
+		return rcv._tab.ByteVector(a + flatbuffers.UOffsetT(j*4))
+	}
+	return nil
+}
+
+func (rcv *FdbxStage) ArgsLength() int {
+	o := flatbuffers.UOffsetT(rcv._tab.Offset(20))
+	if o != 0 {
+		return rcv._tab.VectorLen(o)
+	}
+	return 0
+}
+
 func FdbxStageStart(builder *flatbuffers.Builder) {
-	builder.StartObject(4)
+	builder.StartObject(9)
 }
 func FdbxStageAddDur(builder *flatbuffers.Builder, dur int64) {
 	builder.PrependInt64Slot(0, dur, 0)
@@ -118,6 +209,24 @@ func FdbxStageAddMid(builder *flatbuffers.Builder, mid flatbuffers.UOffsetT) {
 func FdbxStageAddMsg(builder *flatbuffers.Builder, msg flatbuffers.UOffsetT) {
 	builder.PrependUOffsetTSlot(3, flatbuffers.UOffsetT(msg), 0)
 }
+func FdbxStageAddVerb(builder *flatbuffers.Builder, verb int32) {
+	builder.PrependInt32Slot(4, verb, 0)
+}
+func FdbxStageAddCode(builder *flatbuffers.Builder, code flatbuffers.UOffsetT) {
+	builder.PrependUOffsetTSlot(5, flatbuffers.UOffsetT(code), 0)
+}
+func FdbxStageAddStatus(builder *flatbuffers.Builder, status uint16) {
+	builder.PrependUint16Slot(6, status, 0)
+}
+func FdbxStageAddTmpl(builder *flatbuffers.Builder, tmpl flatbuffers.UOffsetT) {
+	builder.PrependUOffsetTSlot(7, flatbuffers.UOffsetT(tmpl), 0)
+}
+func FdbxStageAddArgs(builder *flatbuffers.Builder, args flatbuffers.UOffsetT) {
+	builder.PrependUOffsetTSlot(8, flatbuffers.UOffsetT(args), 0)
+}
+func FdbxStageStartArgsVector(builder *flatbuffers.Builder, numElems int) flatbuffers.UOffsetT {
+	return builder.StartVector(4, numElems, 4)
+}
 func FdbxStageEnd(builder *flatbuffers.Builder) flatbuffers.UOffsetT {
 	return builder.EndObject()
 }
@@ -1,6 +1,7 @@
 package journal
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -17,19 +18,23 @@ import (
 	* drv - реализация драйвера для сохранения записи журнала
 	* log - реализация логгера для записи журнала в консольку или файл
 	* srv - наименование сервиса, инициатора записей в журнале
+	* opts - дополнительные настройки, переходят и в копии из Clone
 */
-func NewProvider(max int, crp crash.Provider, drv Driver, log Logger, srv string) Provider {
+func NewProvider(max int, crp crash.Provider, drv Driver, log Logger, srv string, opts ...ProviderOption) Provider {
 	if log == nil {
 		log = new(GlogLogger)
 	}
 
 	p := &provider{
+		id:    typex.NewUUID().Hex(),
 		max:   max,
 		drv:   drv,
 		crp:   crp,
 		log:   log,
 		srv:   srv,
 		lvl:   0,
+		opt:   opts,
+		tmpl:  getProviderOptions(opts).tmpl,
 		start: time.Now(),
 		chain: make([]*Stage, 0, 16),
 	}
@@ -37,10 +42,41 @@ func NewProvider(max int, crp crash.Provider, drv Driver, log Logger, srv string
 	return p
 }
 
+// ProviderOption - настройка сборщика журнала
+type ProviderOption func(*providerOptions)
+
+/*
+	ProviderTemplates - сохранение в отметках шаблона и аргументов отдельно от текста.
+
+	* Текст форматируется в момент вызова Print или Model, шаблон и аргументы - только дополнительные данные к нему
+	* По шаблону похожие отметки группируются в поиске, агрегатах и распределении времени
+	* Без этой настройки в отметках только текст, как и в драйверах, которые шаблоны не хранят
+*/
+func ProviderTemplates() ProviderOption {
+	return func(o *providerOptions) {
+		o.tmpl = true
+	}
+}
+
+type providerOptions struct {
+	tmpl bool
+}
+
+func getProviderOptions(args []ProviderOption) *providerOptions {
+	o := new(providerOptions)
+
+	for i := range args {
+		args[i](o)
+	}
+
+	return o
+}
+
 type provider struct {
 	sync.RWMutex
 
 	crash bool
+	id    string
 	point time.Time
 	start time.Time
 	chain []*Stage
@@ -51,6 +87,9 @@ type provider struct {
 	crp crash.Provider
 	max int
 	lvl int
+
+	opt  []ProviderOption
+	tmpl bool
 }
 
 func (p *provider) V(lvl int) bool {
@@ -63,22 +102,34 @@ func (p *provider) V(lvl int) bool {
 }
 
 func (p *provider) Print(txt string, args ...interface{}) {
-	p.stage(&Stage{
-		Text: fmt.Sprintf(txt, args...),
-	})
+	p.stage(new(Stage).format(txt, args, p.tmpl))
 }
 
 func (p *provider) Model(mtp ModelType, mid string, txt string, args ...interface{}) {
-	p.stage(&Stage{
+	p.stage((&Stage{
 		EnID: mid,
 		Type: mtp.ID(),
-		Text: fmt.Sprintf(txt, args...),
-	})
+	}).format(txt, args, p.tmpl))
 }
 
 func (p *provider) Crash(err error) (r *crash.Report) {
+	var prb *crash.Problem
+
 	if r = p.crp.Report(err); r != nil {
+		// Идентификатор записи известен заранее, по нему отчет связан с ней и у клиента
+		if r.Trace == "" {
+			r.Trace = p.id
+		}
+
 		p.stage(&Stage{Fail: r})
+
+		// Отчет другого сервиса, из-за которого возникла ошибка, чтобы найти записи по нему
+		if errors.As(err, &prb) && prb.ID != "" {
+			p.stage((&Stage{
+				EnID: prb.ID,
+				Type: ModelTypeRemoteCrash.ID(),
+			}).format("Ошибка другого сервиса: %s", []interface{}{prb.Code}, p.tmpl))
+		}
 	}
 	return r
 }
@@ -87,7 +138,7 @@ func (p *provider) Close() *Entry {
 	var err error
 
 	e := &Entry{
-		ID:      typex.NewUUID().Hex(),
+		ID:      p.id,
 		Total:   time.Since(p.start),
 		Start:   p.start.UTC(),
 		Chain:   p.chain,
@@ -111,7 +162,7 @@ func (p *provider) Close() *Entry {
 	return e
 }
 
-func (p *provider) Clone() Provider { return NewProvider(p.max, p.crp, p.drv, p.log, p.srv) }
+func (p *provider) Clone() Provider { return NewProvider(p.max, p.crp, p.drv, p.log, p.srv, p.opt...) }
 
 func (p *provider) stage(s *Stage) {
 	if s.Fail != nil {
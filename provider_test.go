@@ -2,6 +2,7 @@ package journal
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/shestakovda/errx"
 	"github.com/shestakovda/journal/crash"
@@ -76,6 +77,108 @@ func (s *ProviderSuite) TestPrint() {
 	// s.True(false)
 }
 
+func (s *ProviderSuite) TestFormatSnapshot() {
+	ids := []int{123}
+	prv := NewProvider(1, s.crp, s.drv, s.log, "", ProviderTemplates())
+
+	// Текст и аргументы фиксируются в момент вызова, последующие изменения их не меняют
+	prv.Print("load user %v", ids)
+	ids[0] = 456
+
+	s.drv.On("InsertEntry", mock.Anything).Return(nil).Once()
+
+	if ent := prv.Close(); s.Len(ent.Chain, 1) {
+		s.Equal("load user [123]", ent.Chain[0].Text)
+		s.Equal("load user %v", ent.Chain[0].Tmpl)
+		s.Equal([]string{"[123]"}, ent.Chain[0].Args)
+	}
+
+	// Копия сохраняет настройку
+	cln := prv.Clone()
+	cln.Print("load user %v", ids)
+	s.drv.On("InsertEntry", mock.Anything).Return(nil).Once()
+
+	if ent := cln.Close(); s.Len(ent.Chain, 1) {
+		s.Equal("load user %v", ent.Chain[0].Tmpl)
+		s.Equal([]string{"[456]"}, ent.Chain[0].Args)
+	}
+}
+
+func (s *ProviderSuite) TestNoTemplates() {
+	s.prv.Print("load user %v", 123)
+	s.drv.On("InsertEntry", mock.Anything).Return(nil).Once()
+
+	// По умолчанию в отметке только текст
+	if ent := s.prv.Close(); s.Len(ent.Chain, 1) {
+		s.Equal("load user 123", ent.Chain[0].Text)
+		s.Empty(ent.Chain[0].Tmpl)
+		s.Empty(ent.Chain[0].Args)
+	}
+}
+
+func (s *ProviderSuite) TestNoArgs() {
+	prv := NewProvider(1, s.crp, s.drv, s.log, "", ProviderTemplates())
+	prv.Print("static text")
+	s.drv.On("InsertEntry", mock.Anything).Return(nil).Once()
+
+	// Шаблон без аргументов не сохраняется, он совпадает с текстом
+	if ent := prv.Close(); s.Len(ent.Chain, 1) {
+		s.Equal("static text", ent.Chain[0].Text)
+		s.Empty(ent.Chain[0].Tmpl)
+		s.Empty(ent.Chain[0].Args)
+	}
+}
+
+func (s *ProviderSuite) TestLocalize() {
+	ent := Entry{ID: "id", Service: "srv", Total: time.Second}
+
+	s.Equal(ent.String(), ent.Localize(""))
+	s.Contains(ent.String(), "Запись: id")
+	s.Contains(ent.Localize("en-US,en;q=0.9"), "Entry: id")
+	s.Contains(ent.Localize("en-US,en;q=0.9"), "Total: 1s")
+	s.Contains(ent.Localize("de"), "Сервис: srv")
+}
+
+func (s *ProviderSuite) TestRemoteCrash() {
+	err := errx.ErrForbidden.WithReason(&crash.Problem{ID: "remote", Code: "other4041", Status: 404, Title: "not found"})
+
+	s.drv.On("InsertEntry", mock.Anything).Return(nil).Once()
+
+	rep := s.prv.Crash(err)
+	s.Require().NotNil(rep)
+
+	if ent := s.prv.Close(); s.Len(ent.Chain, 2) {
+		s.Equal(ent.ID, rep.Trace)
+		s.Equal(ModelTypeCrash.ID(), ent.Chain[0].Type)
+		s.Equal("remote", ent.Chain[1].EnID)
+		s.Equal(ModelTypeRemoteCrash.ID(), ent.Chain[1].Type)
+		s.Equal("Ошибка другого сервиса: other4041", ent.Chain[1].Text)
+	}
+
+	// Тип приложения с тем же номером не заменяет тип журнала, а вместе с ним не регистрируются и остальные
+	s.Panics(func() {
+		RegisterType(mType{id: 0x4242, name: "other"}, mType{id: ModelTypeRemoteCrash.ID(), name: "custom"})
+	})
+	s.Equal("remote_crash", getType(ModelTypeRemoteCrash.ID()).String())
+	s.Equal("unknown", getType(0x4242).String())
+}
+
+func (s *ProviderSuite) TestInsertFailRender() {
+	err := errx.ErrInternal.WithReason(&crash.Problem{ID: "remote", Code: "other5001", Status: 500, Title: "db failure"})
+
+	s.prv.Print("ololo %s", "test")
+	s.drv.On("InsertEntry", mock.Anything).Return(err).Once()
+
+	// Отметки, добавленные из-за ошибки сохранения, тоже отформатированы
+	if ent := s.prv.Close(); s.Len(ent.Chain, 3) {
+		s.Equal(ModelTypeCrash.ID(), ent.Chain[1].Type)
+		s.Equal(ModelTypeRemoteCrash.ID(), ent.Chain[2].Type)
+		s.Equal("Ошибка другого сервиса: other5001", ent.Chain[2].Text)
+	}
+
+	s.Contains(s.log.Result, "Ошибка другого сервиса: other5001")
+}
+
 type MockDriver struct{ mock.Mock }
 
 func (m *MockDriver) InsertEntry(e *Entry) error { return m.Called(e).Error(0) }
@@ -0,0 +1,124 @@
+package journal
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// SketchAccuracy - относительная точность процентилей, которые возвращает Sketch
+const SketchAccuracy = 0.01
+
+// Основание логарифмических корзин, при котором середина корзины отличается от краев не больше чем на точность
+var sketchGamma = (1 + SketchAccuracy) / (1 - SketchAccuracy)
+var sketchLogGamma = math.Log(sketchGamma)
+
+// Особый номер корзины для нулевых длительностей, вне диапазона обычных корзин
+const sketchZeroBin int32 = math.MinInt32
+
+/*
+	Sketch - набросок распределения длительностей для расчета процентилей.
+
+	* Длительности раскладываются по логарифмическим корзинам, сами значения не хранятся
+	* Наброски одинаковой точности можно складывать, например по соседним интервалам времени
+	* Процентиль отличается от точного значения не больше чем на SketchAccuracy
+*/
+type Sketch struct {
+	Count uint64           `json:"count"`
+	Zero  uint64           `json:"zero,omitempty"`
+	Bins  map[int32]uint64 `json:"bins,omitempty"`
+}
+
+// NewSketch - конструктор пустого наброска
+func NewSketch() *Sketch {
+	return &Sketch{Bins: make(map[int32]uint64)}
+}
+
+// Add - учет одной длительности
+func (s *Sketch) Add(d time.Duration) {
+	s.addBin(sketchBin(d), 1)
+}
+
+// Merge - добавление всех значений другого наброска
+func (s *Sketch) Merge(o *Sketch) {
+	if o == nil {
+		return
+	}
+
+	s.addBin(sketchZeroBin, o.Zero)
+
+	for bin, cnt := range o.Bins {
+		s.addBin(bin, cnt)
+	}
+}
+
+/*
+	Quantile - оценка процентиля распределения.
+
+	* q - доля от 0 до 1, например 0.99 для p99
+
+	* Если набросок пустой, возвращает 0
+*/
+func (s *Sketch) Quantile(q float64) time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+
+	if q < 0 {
+		q = 0
+	} else if q > 1 {
+		q = 1
+	}
+
+	rank := uint64(q * float64(s.Count-1))
+
+	if rank < s.Zero {
+		return 0
+	}
+
+	bins := make([]int32, 0, len(s.Bins))
+	for bin := range s.Bins {
+		bins = append(bins, bin)
+	}
+
+	sort.Slice(bins, func(i, j int) bool { return bins[i] < bins[j] })
+
+	seen := s.Zero
+
+	for _, bin := range bins {
+		if seen += s.Bins[bin]; seen > rank {
+			return sketchValue(bin)
+		}
+	}
+
+	return sketchValue(bins[len(bins)-1])
+}
+
+func (s *Sketch) addBin(bin int32, cnt uint64) {
+	if bin == sketchZeroBin {
+		s.Zero += cnt
+		s.Count += cnt
+		return
+	}
+
+	if s.Bins == nil {
+		s.Bins = make(map[int32]uint64)
+	}
+
+	s.Bins[bin] += cnt
+	s.Count += cnt
+}
+
+// sketchBin - номер корзины длительности, корзина i содержит значения из (gamma^(i-1), gamma^i]
+func sketchBin(d time.Duration) int32 {
+	if d <= 0 {
+		return sketchZeroBin
+	}
+
+	return int32(math.Ceil(math.Log(float64(d)) / sketchLogGamma))
+}
+
+// sketchValue - оценка значения корзины, равноудаленная в относительной мере от ее краев
+func sketchValue(bin int32) time.Duration {
+	return time.Duration(2 * math.Pow(sketchGamma, float64(bin)) / (sketchGamma + 1))
+}
@@ -0,0 +1,55 @@
+package journal
+
+import (
+	"math"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SketchSuite struct {
+	suite.Suite
+}
+
+func (s *SketchSuite) TestQuantile() {
+	sk := NewSketch()
+
+	// Пустой набросок
+	s.Equal(time.Duration(0), sk.Quantile(0.5))
+
+	for i := 1; i <= 1000; i++ {
+		sk.Add(time.Duration(i) * time.Millisecond)
+	}
+
+	s.Equal(uint64(1000), sk.Count)
+
+	// Оценка не дальше заявленной точности от точного значения
+	for _, q := range []float64{0.5, 0.9, 0.99} {
+		exp := float64(1+int(q*999)) * float64(time.Millisecond)
+		s.InDelta(exp, float64(sk.Quantile(q)), exp*SketchAccuracy, "q = %v", q)
+	}
+}
+
+func (s *SketchSuite) TestMerge() {
+	sk1 := NewSketch()
+	sk2 := NewSketch()
+	all := NewSketch()
+
+	for i := 0; i < 500; i++ {
+		sk1.Add(time.Duration(i) * time.Microsecond)
+		all.Add(time.Duration(i) * time.Microsecond)
+	}
+
+	for i := 500; i < 1000; i++ {
+		sk2.Add(time.Duration(i) * time.Second)
+		all.Add(time.Duration(i) * time.Second)
+	}
+
+	// Сумма набросков равна наброску по всем значениям
+	sk1.Merge(sk2)
+	s.Equal(all, sk1)
+	s.Equal(uint64(1), sk1.Zero)
+
+	// Оценка корзины близка к исходной длительности
+	s.True(math.Abs(float64(sketchValue(sketchBin(time.Hour))-time.Hour)) <= float64(time.Hour)*SketchAccuracy)
+}
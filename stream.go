@@ -0,0 +1,127 @@
+package journal
+
+import (
+	"context"
+	"time"
+
+	"github.com/shestakovda/journal/crash"
+)
+
+// pager - постраничная загрузка, общая для всех курсоров
+type pager interface {
+	Empty() bool
+	NextPage(size uint, services ...string) ([]Model, error)
+}
+
+/*
+	renewer - курсор, который может продолжить долгий перебор в новой транзакции.
+
+	* release завершает все новые транзакции и возвращает курсор в транзакцию фабрики
+*/
+type renewer interface {
+	renew() error
+	release() error
+}
+
+// eachModel - после перебора новые транзакции завершаются, все записи к этому моменту уже обработаны
+func eachModel(ctx context.Context, cur pager, size uint, fn func(Model) error) (err error) {
+	if ren, ok := cur.(renewer); ok {
+		defer func() {
+			if exp := ren.release(); err == nil {
+				err = exp
+			}
+		}()
+	}
+
+	return scanModels(ctx, cur, size, fn)
+}
+
+// streamModels - записи могут обрабатываться и после перебора, поэтому новые транзакции завершаются в Close курсора
+func streamModels(ctx context.Context, cur pager, size uint) (<-chan Model, <-chan error) {
+	// Без буфера заранее загружена только текущая страница
+	res := make(chan Model)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(res)
+
+		push := func(mod Model) error {
+			select {
+			case res <- mod:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if err := scanModels(ctx, cur, size, push); err != nil {
+			errs <- err
+		}
+	}()
+
+	return res, errs
+}
+
+func scanModels(ctx context.Context, cur pager, size uint, fn func(Model) error) (err error) {
+	var mods []Model
+
+	if size == 0 {
+		return ErrValidate.WithDetail("Не указан размер страницы")
+	}
+
+	ren, _ := cur.(renewer)
+	mark := time.Now()
+
+	for !cur.Empty() {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		if ren != nil && time.Since(mark) > StreamRenew {
+			if err = ren.renew(); err != nil {
+				return err
+			}
+
+			mark = time.Now()
+		}
+
+		if mods, err = cur.NextPage(size); err != nil {
+			return err
+		}
+
+		for i := range mods {
+			if err = fn(mods[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func nextSummary(cur pager, size uint, services []string) (res []*Summary, err error) {
+	var mods []Model
+
+	if mods, err = cur.NextPage(size, services...); err != nil {
+		return nil, err
+	}
+
+	res = make([]*Summary, len(mods))
+
+	for i := range mods {
+		res[i] = mods[i].ExportSummary()
+	}
+
+	return res, nil
+}
+
+func nextExport(cur pager, crf crash.Factory, size uint, mode ExportMode, services []string) (_ []*Entry, err error) {
+	var mods []Model
+
+	if mods, err = cur.NextPage(size, services...); err != nil {
+		return nil, err
+	}
+
+	return exportEntries(crf, mods, mode)
+}
@@ -0,0 +1,85 @@
+package journal
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type StreamSuite struct {
+	suite.Suite
+}
+
+func (s *StreamSuite) TestEachRelease() {
+	cur := &testPager{size: 5}
+
+	defer func(renew time.Duration) { StreamRenew = renew }(StreamRenew)
+	StreamRenew = 0
+
+	cnt := 0
+	s.NoError(eachModel(context.Background(), cur, 2, func(Model) error {
+		// Пока идет перебор, транзакции не завершаются
+		s.Zero(atomic.LoadInt32(&cur.released))
+		cnt++
+		return nil
+	}))
+
+	s.Equal(5, cnt)
+	s.Equal(int32(3), atomic.LoadInt32(&cur.renewed))
+	s.Equal(int32(1), atomic.LoadInt32(&cur.released))
+}
+
+func (s *StreamSuite) TestStreamPrefetch() {
+	cur := &testPager{size: 5}
+	mods, errs := streamModels(context.Background(), cur, 2)
+
+	// Следующая страница загружается, только когда текущая полностью выдана
+	<-mods
+	s.Equal(int32(1), atomic.LoadInt32(&cur.loaded))
+	<-mods
+	<-mods
+	s.Equal(int32(2), atomic.LoadInt32(&cur.loaded))
+
+	for range mods {
+	}
+
+	s.NoError(<-errs)
+
+	// Записи могут обрабатываться и после перебора, поэтому транзакции завершаются только в Close
+	s.Zero(atomic.LoadInt32(&cur.released))
+}
+
+type testPager struct {
+	size     int
+	loaded   int32
+	renewed  int32
+	released int32
+}
+
+func (p *testPager) Empty() bool {
+	return p.size == 0
+}
+
+func (p *testPager) NextPage(size uint, _ ...string) ([]Model, error) {
+	n := int(size)
+
+	if n > p.size {
+		n = p.size
+	}
+
+	p.size -= n
+	atomic.AddInt32(&p.loaded, 1)
+	return make([]Model, n), nil
+}
+
+func (p *testPager) renew() error {
+	atomic.AddInt32(&p.renewed, 1)
+	return nil
+}
+
+func (p *testPager) release() error {
+	atomic.AddInt32(&p.released, 1)
+	return nil
+}
@@ -0,0 +1,84 @@
+package journal
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"time"
+
+	"github.com/shestakovda/errx"
+	"github.com/shestakovda/typex"
+)
+
+const verTokenV1 byte = 1
+
+// newToken - маркер позиции в переборе, содержит момент старта и идентификатор последней записи
+func newToken(start time.Time, uid typex.UUID) string {
+	buf := make([]byte, 9+len(uid))
+	buf[0] = verTokenV1
+	binary.BigEndian.PutUint64(buf[1:9], uint64(start.UTC().UnixNano()))
+	copy(buf[9:], uid)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// parseToken - разбор маркера позиции, пустой маркер означает начало перебора
+func parseToken(token string) (start time.Time, uid typex.UUID, err error) {
+	var buf []byte
+
+	if token == "" {
+		return start, nil, nil
+	}
+
+	if buf, err = base64.RawURLEncoding.DecodeString(token); err != nil {
+		return start, nil, ErrValidate.WithReason(err).WithDetail("Некорректный маркер позиции")
+	}
+
+	if len(buf) < 10 || buf[0] != verTokenV1 {
+		return start, nil, ErrValidate.WithDetail("Некорректный маркер позиции").WithDebug(errx.Debug{
+			"Маркер": token,
+		})
+	}
+
+	start = time.Unix(0, int64(binary.BigEndian.Uint64(buf[1:9]))).UTC()
+	uid = typex.UUID(buf[9:])
+	return start, uid, nil
+}
+
+// keyBefore - ключ, который вместе с любым суффиксом меньше исходного ключа той же длины
+func keyBefore(key []byte) []byte {
+	res := make([]byte, len(key))
+	copy(res, key)
+
+	for i := len(res) - 1; i >= 0; i-- {
+		if res[i] > 0 {
+			res[i]--
+			return res
+		}
+
+		res[i] = 0xFF
+	}
+
+	// Меньше нулевого ключа быть ничего не может, оставляем пустой интервал
+	return nil
+}
+
+// checkOrder - проверка порядка перебора, неизвестное значение не считаем ни одним из допустимых
+// checkSlowOrder - порядок перебора медленных записей должен быть одним из известных
+func checkSlowOrder(ord SlowOrder) error {
+	if ord != SlowByTime && ord != SlowByDuration {
+		return ErrValidate.WithDetail("Некорректный порядок перебора").WithDebug(errx.Debug{
+			"Порядок": ord,
+		})
+	}
+
+	return nil
+}
+
+func checkOrder(ord Order) error {
+	if ord != OrderDesc && ord != OrderAsc {
+		return ErrValidate.WithDetail("Некорректный порядок перебора").WithDebug(errx.Debug{
+			"Порядок": ord,
+		})
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package journal
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -19,19 +20,40 @@ type Entry struct {
 	Chain   []*Stage
 }
 
+// EntryLabels - подписи текстового представления записи на одном языке
+type EntryLabels struct {
+	Entry   string
+	Start   string
+	Service string
+	Total   string
+}
+
+// Labels - подписи текстового представления записи по тегам языков, если подходящего нет - на языке crash.DefaultLang
+var Labels = map[string]*EntryLabels{
+	"ru": {Entry: "Запись", Start: "Старт", Service: "Сервис", Total: "Всего"},
+	"en": {Entry: "Entry", Start: "Start", Service: "Service", Total: "Total"},
+}
+
 func (v Entry) String() string {
+	return v.Localize("")
+}
+
+// Localize - текстовое представление с подписями на языке, подобранном по значению Accept-Language
+func (v Entry) Localize(accept string) string {
 	const newline byte = '\n'
 	var buf strings.Builder
 
+	lbl := entryLabels(accept)
+
 	// Примерная прикидка, чтобы сэкономить в большинстве случаев
 	buf.Grow(64 + len(v.ID) + 32 + len(v.Service) + 256*len(v.Chain))
 
 	// Заголовок записи
-	buf.WriteString("Запись: ")
+	buf.WriteString(lbl.Entry + ": ")
 	buf.WriteString(v.ID)
-	buf.WriteString(" Старт: ")
+	buf.WriteString(" " + lbl.Start + ": ")
 	buf.WriteString(v.Start.Format(time.RFC3339Nano))
-	buf.WriteString(" Сервис: ")
+	buf.WriteString(" " + lbl.Service + ": ")
 	buf.WriteString(v.Service)
 	buf.WriteByte(newline)
 
@@ -42,7 +64,7 @@ func (v Entry) String() string {
 	}
 
 	// Подвал
-	buf.WriteString("Всего: ")
+	buf.WriteString(lbl.Total + ": ")
 	buf.WriteString(v.Total.String())
 	buf.WriteByte(newline)
 
@@ -50,16 +72,63 @@ func (v Entry) String() string {
 	return buf.String()
 }
 
+// entryLabels - подписи на подходящем языке, если такого нет, то на языке по-умолчанию
+func entryLabels(accept string) *EntryLabels {
+	if accept != "" {
+		langs := make([]string, 0, len(Labels))
+
+		for lang := range Labels {
+			langs = append(langs, lang)
+		}
+
+		sort.Strings(langs)
+
+		if lang := crash.MatchLang(accept, langs...); lang != "" {
+			return Labels[lang]
+		}
+	}
+
+	if lbl, ok := Labels[crash.DefaultLang]; ok {
+		return lbl
+	}
+
+	return defaultLabels
+}
+
+// Подписи, если в Labels нет даже языка по-умолчанию
+var defaultLabels = &EntryLabels{Entry: "Запись", Start: "Старт", Service: "Сервис", Total: "Всего"}
+
 // Stage - основное представление отметки в записи журнала
 type Stage struct {
 	EnID string
 	Text string
+	Tmpl string
+	Args []string
 	Wait time.Duration
 	Verb int
 	Type int
 	Fail *crash.Report
 }
 
+// format - форматирование текста по шаблону в момент вызова, шаблон и аргументы остаются только по запросу
+func (v *Stage) format(tmpl string, args []interface{}, keep bool) *Stage {
+	v.Text = fmt.Sprintf(tmpl, args...)
+
+	// Без аргументов шаблон только повторял бы текст
+	if !keep || len(args) == 0 {
+		return v
+	}
+
+	v.Tmpl = tmpl
+	v.Args = make([]string, len(args))
+
+	for i := range args {
+		v.Args[i] = fmt.Sprint(args[i])
+	}
+
+	return v
+}
+
 func (v Stage) String() string {
 	const wlen = 16
 	const elen = 54
@@ -101,6 +170,17 @@ func (v Stage) String() string {
 	return buf.String()
 }
 
+// Summary - краткое представление записи журнала для списков, без цепочки отметок
+type Summary struct {
+	ID      string        `json:"id,omitempty"`
+	Service string        `json:"service"`
+	Name    string        `json:"name"`
+	Start   time.Time     `json:"start"`
+	Total   time.Duration `json:"total"`
+	Fail    bool          `json:"fail,omitempty"`
+	Tmpl    string        `json:"tmpl,omitempty"`
+}
+
 type API struct {
 	ID     string            `json:"id,omitempty"`
 	Start  time.Time         `json:"start"`
@@ -117,6 +197,7 @@ type StageAPI struct {
 	Name string        `json:"name"`
 	Type string        `json:"type,omitempty"`
 	EnID string        `json:"enid,omitempty"`
+	Fail *crash.RFC    `json:"fail,omitempty"`
 }
 
 type ViewMonitoring struct {